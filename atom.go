@@ -1,6 +1,11 @@
 package main
 
-import "time"
+import (
+	"net/url"
+	"time"
+
+	"golang.org/x/xerrors"
+)
 
 // Category is a category of an Atom entry.
 type Category struct {
@@ -21,6 +26,26 @@ type Entry struct {
 	AuthorURI  string `xml:"author>uri,omitempty"`
 
 	Categories []*Category `xml:"category"`
+
+	// XMLBase is an optional `xml:base` attribute on the entry that overrides
+	// the feed's for relative URLs in its content.
+	XMLBase string `xml:"http://www.w3.org/XML/1998/namespace base,attr,omitempty"`
+
+	// BaseURL is the URL against which relative URLs in the entry's content
+	// are resolved. It's not part of the feed, but rather assigned after
+	// fetching it.
+	BaseURL *url.URL `xml:"-"`
+
+	// FeedPriority and Layout come from the configuration of the entry's
+	// feed. Like BaseURL, they're assigned after fetching it. An empty Layout
+	// is defaultLayout.
+	FeedPriority int    `xml:"-"`
+	Layout       string `xml:"-"`
+
+	// ContentEscaped is set when the entry's content was converted from plain
+	// text, so entities in it are escaped text that must stay escaped when
+	// the entry is rendered rather than being unescaped to save space.
+	ContentEscaped bool `xml:"-"`
 }
 
 // EntryContent is a simple helper class that allows us to wrap an entry's
@@ -36,6 +61,9 @@ type EntryContent struct {
 type Feed struct {
 	XMLName struct{} `xml:"feed"`
 
+	// Go's XML decoder resolves the `xml` prefix to its full namespace, so
+	// `xml:base` must be referenced by it.
+	XMLBase string `xml:"http://www.w3.org/XML/1998/namespace base,attr,omitempty"`
 	XMLLang string `xml:"xml:lang,attr"`
 	XMLNS   string `xml:"xmlns,attr"`
 
@@ -54,6 +82,64 @@ type Link struct {
 	Href string `xml:"href,attr"`
 }
 
+// AssignBaseURLs sets BaseURL on each of the feed's entries. If override is
+// non-nil, it's used for every entry. Otherwise, a base is derived from the
+// feed's `xml:base`, its alternate link, or failing both, the URL the
+// feed was fetched from. An entry's own `xml:base` is resolved against the
+// feed's base and takes precedence over it.
+func (f *Feed) AssignBaseURLs(feedURL string, override *url.URL) error {
+	if override != nil {
+		for _, entry := range f.Entries {
+			entry.BaseURL = override
+		}
+		return nil
+	}
+
+	baseURL, err := url.Parse(feedURL)
+	if err != nil {
+		return xerrors.Errorf("error parsing feed URL %q: %w", feedURL, err)
+	}
+
+	// xml:base takes precedence over the alternate link, but is itself
+	// potentially relative to the feed's URL.
+	if f.XMLBase != "" {
+		if baseURL, err = resolveBaseURL(baseURL, f.XMLBase); err != nil {
+			return err
+		}
+	} else {
+		for _, link := range f.Links {
+			// A link without a rel is an alternate link (RFC 4287 §4.2.7.2).
+			if (link.Rel == "" || link.Rel == "alternate") && link.Href != "" {
+				if baseURL, err = resolveBaseURL(baseURL, link.Href); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	for _, entry := range f.Entries {
+		entry.BaseURL = baseURL
+
+		if entry.XMLBase != "" {
+			if entry.BaseURL, err = resolveBaseURL(baseURL, entry.XMLBase); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func resolveBaseURL(baseURL *url.URL, ref string) (*url.URL, error) {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return nil, xerrors.Errorf("error parsing base URL %q: %w", ref, err)
+	}
+
+	return baseURL.ResolveReference(refURL), nil
+}
+
 func sortEntriesDesc(a, b *Entry) bool {
 	return b.Published.Before(a.Published)
 }
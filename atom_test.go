@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/xml"
+	"net/url"
 	"testing"
 	"time"
 
@@ -20,3 +22,73 @@ func TestSortEntriesDesc(t *testing.T) {
 
 	require.Equal(t, []*Entry{e3, e2, e1}, es)
 }
+
+func TestFeedAssignBaseURLs(t *testing.T) {
+	const feedURL = "https://example.com/feeds/sequences.atom"
+
+	assignBaseURLs := func(t *testing.T, data string, override string) []string {
+		t.Helper()
+
+		var feed Feed
+		require.NoError(t, xml.Unmarshal([]byte(data), &feed))
+
+		var overrideURL *url.URL
+		if override != "" {
+			overrideURL = mustParseURL(override)
+		}
+		require.NoError(t, feed.AssignBaseURLs(feedURL, overrideURL))
+
+		baseURLs := make([]string, len(feed.Entries))
+		for i, entry := range feed.Entries {
+			baseURLs[i] = entry.BaseURL.String()
+		}
+		return baseURLs
+	}
+
+	t.Run("Override", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"https://brandur.org/"},
+			assignBaseURLs(t, `<feed xml:base="https://other.example.com/"><entry></entry></feed>`, "https://brandur.org/"),
+		)
+	})
+
+	t.Run("XMLBase", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"https://example.com/blog/", "https://example.com/blog/posts/"},
+			assignBaseURLs(t, `<feed xml:base="/blog/">
+				<link rel="alternate" href="https://example.com/alternate" />
+				<entry></entry>
+				<entry xml:base="posts/"></entry>
+			</feed>`, ""),
+		)
+	})
+
+	t.Run("AlternateLink", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"https://example.com/sequences"},
+			assignBaseURLs(t, `<feed>
+				<link rel="self" href="https://example.com/feeds/sequences.atom" />
+				<link rel="alternate" href="/sequences" />
+				<entry></entry>
+			</feed>`, ""),
+		)
+	})
+
+	t.Run("LinkWithoutRel", func(t *testing.T) {
+		require.Equal(t,
+			[]string{"https://example.org/blog/"},
+			assignBaseURLs(t, `<feed>
+				<link rel="self" href="https://example.com/feeds/sequences.atom" />
+				<link href="https://example.org/blog/" />
+				<entry></entry>
+			</feed>`, ""),
+		)
+	})
+
+	t.Run("FeedURL", func(t *testing.T) {
+		require.Equal(t,
+			[]string{feedURL},
+			assignBaseURLs(t, `<feed><entry></entry></feed>`, ""),
+		)
+	})
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// The maximum size of a board in bytes as dictated by the Spring '83
+// specification.
+const maxBoardSize = 2217
+
+// The amount by which the content budget is reduced between truncation
+// attempts in case the previous attempt didn't fit.
+const truncateStep = 100
+
+// ErrBoardTooLarge is returned when no fallback rendering of an entry fits
+// within maxBoardSize.
+var ErrBoardTooLarge = xerrors.New("board is too large")
+
+// renderedBoard is a board that's ready to be signed and published.
+type renderedBoard struct {
+	Content string
+
+	// Timestamp is the time in the board's `<time>` element, which is also
+	// used for any redirect boards published alongside it.
+	Timestamp time.Time
+}
+
+// boardFallback is a strategy for producing an entry's content. They're tried
+// in order, with each successive fallback producing a smaller board than the
+// last, until one fits within maxBoardSize.
+type boardFallback struct {
+	Name string
+
+	// Candidates produces possible contents for the entry, ordered from largest
+	// to smallest. May return nil if the fallback isn't applicable to the
+	// entry (e.g. there's no summary). Budget is the number of bytes left for
+	// content after accounting for the layout around it.
+	Candidates func(entry *Entry, budget int) ([]string, error)
+}
+
+var boardFallbacks = []boardFallback{
+	{
+		Name: "none",
+		Candidates: func(entry *Entry, budget int) ([]string, error) {
+			// An empty body always fits, so give an entry with only a summary
+			// a chance to use it.
+			if entryContent(entry) == "" && entry.Summary != "" {
+				return nil, nil
+			}
+			return []string{entryContent(entry)}, nil
+		},
+	},
+	{
+		Name: "drop_images",
+		Candidates: func(entry *Entry, budget int) ([]string, error) {
+			if entryContent(entry) == "" {
+				return nil, nil
+			}
+			return []string{stripMedia(entryContent(entry))}, nil
+		},
+	},
+	{
+		Name: "summary",
+		Candidates: func(entry *Entry, budget int) ([]string, error) {
+			if entry.Summary == "" {
+				return nil, nil
+			}
+			return []string{"<p>" + entry.Summary + "</p>"}, nil
+		},
+	},
+	{
+		Name: "truncate",
+		Candidates: func(entry *Entry, budget int) ([]string, error) {
+			// Canonicalize before truncating so that the longer URLs are counted
+			// against the budget. Minification after the fact may shrink content
+			// further, but never grow it, so the first candidate will usually
+			// fit, but step down a little at a time in case it doesn't.
+			content := stripMedia(entryContent(entry))
+			if entry.BaseURL != nil {
+				content = canonicalizeURLs(content, entry.BaseURL)
+			}
+
+			var continueURL string
+			if entry.Link != nil {
+				continueURL = entry.Link.Href
+			}
+
+			var candidates []string
+			for ; budget > 0; budget -= truncateStep {
+				truncated, _, err := truncateHTML(content, budget, continueURL)
+				if err != nil {
+					return nil, err
+				}
+
+				// Nothing could be kept, and smaller budgets won't fare any
+				// better.
+				if truncated == "" {
+					break
+				}
+
+				candidates = append(candidates, truncated)
+			}
+			return candidates, nil
+		},
+	},
+}
+
+// Renders the given entry into a board that fits within maxBoardSize, trying
+// progressively smaller fallbacks until one does. Returns the rendered board
+// along with the name of the fallback that was used.
+func renderBoard(entry *Entry) (string, string, error) {
+	empty, err := renderBoardContent(entry, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	budget := maxBoardSize - len(empty)
+
+	var smallest int
+
+	for _, fallback := range boardFallbacks {
+		candidates, err := fallback.Candidates(entry, budget)
+		if err != nil {
+			return "", "", err
+		}
+
+		for _, content := range candidates {
+			rendered, err := renderBoardContent(entry, content)
+			if err != nil {
+				return "", "", err
+			}
+
+			if len(rendered) <= maxBoardSize {
+				return rendered, fallback.Name, nil
+			}
+
+			if smallest == 0 || len(rendered) < smallest {
+				smallest = len(rendered)
+			}
+
+			logger.Infof("Board is %d bytes with fallback %q (max: %d bytes)", len(rendered), fallback.Name, maxBoardSize)
+		}
+	}
+
+	return "", "", xerrors.Errorf("%w: smallest rendering of entry %q is %d bytes (max: %d bytes)",
+		ErrBoardTooLarge, entry.Title, smallest, maxBoardSize)
+}
+
+// Renders the given content for an entry through layout, canonicalization,
+// and minification. The result is what's sent to a Spring '83 server.
+func renderBoardContent(entry *Entry, content string) (string, error) {
+	layout := entry.Layout
+	if layout == "" {
+		layout = defaultLayout
+	}
+
+	rendered, err := renderLayout(layout, entry.Title, content, entry.Published)
+	if err != nil {
+		return "", err
+	}
+
+	if entry.BaseURL != nil {
+		rendered = canonicalizeURLs(rendered, entry.BaseURL)
+	}
+
+	// Text content is escaped into the board, and unescaping it would turn it
+	// into markup.
+	if entry.ContentEscaped {
+		rendered = minimizeWhitespace(rendered)
+	} else {
+		rendered = minimizeContent(rendered)
+	}
+
+	return rendered, nil
+}
+
+// Gets an entry's content, which may be empty in case the feed only included
+// a summary.
+func entryContent(entry *Entry) string {
+	if entry.Content == nil {
+		return ""
+	}
+	return entry.Content.Content
+}
+
+var (
+	imgRE     = regexp.MustCompile(`(?is)<img[^>]*>`)
+	pictureRE = regexp.MustCompile(`(?is)<picture[^>]*>.*?</picture>`)
+	videoRE   = regexp.MustCompile(`(?is)<video[^>]*>.*?</video>`)
+)
+
+// Strips images and videos from content. These are by far the largest
+// contributor to a board's size once canonicalized.
+func stripMedia(content string) string {
+	content = pictureRE.ReplaceAllString(content, "")
+	content = videoRE.ReplaceAllString(content, "")
+	content = imgRE.ReplaceAllString(content, "")
+	return strings.TrimSpace(content)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderBoard(t *testing.T) {
+	newEntry := func(content, summary string) *Entry {
+		return &Entry{
+			Title:     "a title",
+			Summary:   summary,
+			Content:   &EntryContent{Content: content},
+			Published: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+			BaseURL:   sampleBaseURL,
+		}
+	}
+
+	t.Run("FitsAsIs", func(t *testing.T) {
+		rendered, fallback, err := renderBoard(newEntry("<p>short</p>", ""))
+		require.NoError(t, err)
+		require.Equal(t, "none", fallback)
+		require.Contains(t, rendered, "<p>short</p>")
+	})
+
+	t.Run("DropImages", func(t *testing.T) {
+		// Drop the last paragraph, but keep images.
+		lastParagraph := strings.LastIndex(sampleContent, "<p>")
+		content := sampleContent[0:lastParagraph] + sampleContent[strings.Index(sampleContent, "<img"):]
+
+		rendered, fallback, err := renderBoard(newEntry(content, ""))
+		require.NoError(t, err)
+		require.Equal(t, "drop_images", fallback)
+		require.NotContains(t, rendered, "<img")
+		require.LessOrEqual(t, len(rendered), maxBoardSize)
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		rendered, fallback, err := renderBoard(newEntry(sampleContent+sampleContent, "a summary"))
+		require.NoError(t, err)
+		require.Equal(t, "summary", fallback)
+		require.Contains(t, rendered, "<p>a summary</p>")
+	})
+
+	t.Run("SummaryOnly", func(t *testing.T) {
+		entry := newEntry("", "a real summary")
+		entry.Content = nil
+
+		rendered, fallback, err := renderBoard(entry)
+		require.NoError(t, err)
+		require.Equal(t, "summary", fallback)
+		require.Contains(t, rendered, "<p>a real summary</p>")
+	})
+
+	t.Run("NoContent", func(t *testing.T) {
+		entry := newEntry("", "")
+		entry.Content = nil
+
+		rendered, fallback, err := renderBoard(entry)
+		require.NoError(t, err)
+		require.Equal(t, "none", fallback)
+		require.Contains(t, rendered, "a title")
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		entry := newEntry(sampleContent+sampleContent, "")
+		entry.Link = &Link{Href: "https://brandur.org/sequences/030"}
+
+		rendered, fallback, err := renderBoard(entry)
+		require.NoError(t, err)
+		require.Equal(t, "truncate", fallback)
+		require.True(t, strings.HasSuffix(rendered,
+			`<p><a href="https://brandur.org/sequences/030">`+continueReadingText+`</a></p>`))
+		require.LessOrEqual(t, len(rendered), maxBoardSize)
+	})
+
+	t.Run("NothingFits", func(t *testing.T) {
+		_, _, err := renderBoard(newEntry("<p>"+strings.Repeat("x", maxBoardSize)+"</p>", ""))
+		require.ErrorIs(t, err, ErrBoardTooLarge)
+	})
+}
+
+func TestStripMedia(t *testing.T) {
+	require.Equal(t,
+		"<p>text</p>",
+		stripMedia(`<p>text</p><img src="/a.jpg"><video controls><source src="/a.mp4"></video>`),
+	)
+}
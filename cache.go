@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// cache persists state between runs in a local JSON file so that it works the
+// same in CI and on a laptop. It holds:
+//
+//   - The validators (`ETag` and `Last-Modified`) and body of each fetched
+//     feed so that subsequent fetches can be made conditional, and a `304 Not
+//     Modified` response can be answered from the cache.
+//   - A hash of the last board successfully published to each server and key
+//     so that publishing an identical board can be skipped.
+//   - The ID of the last entry published so that round robin entry selection
+//     knows where it left off.
+//
+// A cache is safe for concurrent use.
+type cache struct {
+	path string
+
+	mu    sync.Mutex
+	state cacheState
+}
+
+// cacheState is the serialized form of a cache.
+type cacheState struct {
+	Boards      map[string]*boardCacheEntry `json:"boards"`
+	Feeds       map[string]*feedCacheEntry  `json:"feeds"`
+	LastEntryID string                      `json:"last_entry_id,omitempty"`
+}
+
+// boardCacheEntry is the cached state of a board published to a single server
+// and key.
+type boardCacheEntry struct {
+	PublishedAt time.Time `json:"published_at"`
+	SHA256      string    `json:"sha256"`
+}
+
+// feedCacheEntry is the cached state of a single feed.
+type feedCacheEntry struct {
+	Body         string    `json:"body"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	LastModified string    `json:"last_modified,omitempty"`
+}
+
+// Loads a cache from the given path. A cache that doesn't exist yet is not an
+// error, and produces an empty cache that'll be created on Save.
+func loadCache(path string) (*cache, error) {
+	c := &cache{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !xerrors.Is(err, fs.ErrNotExist) {
+		return nil, xerrors.Errorf("error reading cache: %w", err)
+	}
+
+	if err == nil {
+		if err := json.Unmarshal(data, &c.state); err != nil {
+			return nil, xerrors.Errorf("error unmarshaling cache %q: %w", path, err)
+		}
+	}
+
+	if c.state.Boards == nil {
+		c.state.Boards = map[string]*boardCacheEntry{}
+	}
+	if c.state.Feeds == nil {
+		c.state.Feeds = map[string]*feedCacheEntry{}
+	}
+
+	return c, nil
+}
+
+// GetBoard gets the cached state of the board last published to the given
+// server and key, or nil if it's not in the cache.
+func (c *cache) GetBoard(springURL, publicKey string) *boardCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state.Boards[boardCacheKey(springURL, publicKey)]
+}
+
+// SetBoard sets the cached state of the board last published to the given
+// server and key. It's not persisted until Save is called.
+func (c *cache) SetBoard(springURL, publicKey string, entry *boardCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.Boards[boardCacheKey(springURL, publicKey)] = entry
+}
+
+// GetFeed gets the cached state of the feed at the given URL, or nil if it's
+// not in the cache.
+func (c *cache) GetFeed(feedURL string) *feedCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state.Feeds[feedURL]
+}
+
+// SetFeed sets the cached state of the feed at the given URL. It's not
+// persisted until Save is called.
+func (c *cache) SetFeed(feedURL string, entry *feedCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.Feeds[feedURL] = entry
+}
+
+// GetLastEntryID gets the ID of the last entry selected for publishing, or an
+// empty string if there isn't one.
+func (c *cache) GetLastEntryID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state.LastEntryID
+}
+
+// SetLastEntryID sets the ID of the last entry selected for publishing. It's
+// not persisted until Save is called.
+func (c *cache) SetLastEntryID(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state.LastEntryID = id
+}
+
+// Save persists the cache to its path. It's written to a temporary file first
+// and renamed into place so that a failure partway through never leaves a
+// corrupt cache behind.
+func (c *cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("error marshaling cache: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return xerrors.Errorf("error creating temporary cache: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return xerrors.Errorf("error writing cache: %w", err)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return xerrors.Errorf("error closing cache: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), c.path); err != nil {
+		return xerrors.Errorf("error moving cache into place: %w", err)
+	}
+
+	return nil
+}
+
+func boardCacheKey(springURL, publicKey string) string {
+	return springURL + "/" + publicKey
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	t.Run("NotExist", func(t *testing.T) {
+		cache, err := loadCache(path)
+		require.NoError(t, err)
+		require.Nil(t, cache.GetBoard("https://spring.example.com", samplePublicKey))
+		require.Nil(t, cache.GetFeed("https://example.com/feed.atom"))
+		require.Empty(t, cache.GetLastEntryID())
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		cache, err := loadCache(path)
+		require.NoError(t, err)
+
+		boardEntry := &boardCacheEntry{
+			PublishedAt: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+			SHA256:      "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		}
+		cache.SetBoard("https://spring.example.com", samplePublicKey, boardEntry)
+
+		feedEntry := &feedCacheEntry{
+			Body:         sampleAtom,
+			ContentType:  "application/atom+xml",
+			ETag:         `"abc123"`,
+			FetchedAt:    time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+			LastModified: "Wed, 09 Nov 2022 10:11:12 GMT",
+		}
+		cache.SetFeed("https://example.com/feed.atom", feedEntry)
+
+		cache.SetLastEntryID("tag:example.com,2022:sequences/030")
+
+		require.NoError(t, cache.Save())
+
+		cache, err = loadCache(path)
+		require.NoError(t, err)
+		require.Equal(t, boardEntry, cache.GetBoard("https://spring.example.com", samplePublicKey))
+		require.Nil(t, cache.GetBoard("https://other.example.com", samplePublicKey))
+		require.Equal(t, feedEntry, cache.GetFeed("https://example.com/feed.atom"))
+		require.Equal(t, "tag:example.com,2022:sequences/030", cache.GetLastEntryID())
+
+		// No temporary files left behind.
+		files, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+		_, err := loadCache(path)
+		require.Error(t, err)
+	})
+}
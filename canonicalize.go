@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Attributes whose values are a single URL, regardless of the tag they appear
+// on.
+var urlAttrs = map[string]bool{
+	"action":     true,
+	"cite":       true,
+	"formaction": true,
+	"href":       true,
+	"poster":     true,
+	"src":        true,
+}
+
+// Rewrites every URL-bearing attribute in content so that relative URLs are
+// resolved against baseURL. This includes root-relative (`/foo`),
+// protocol-relative (`//example.com/foo`), and path-relative (`../foo`) URLs,
+// as well as each candidate in a `srcset`.
+//
+// Content is tokenized rather than parsed so that anything that doesn't need
+// rewriting is emitted exactly as it came in. Only tags with an attribute that
+// changed are re-serialized.
+func canonicalizeURLs(content string, baseURL *url.URL) string {
+	var buf bytes.Buffer
+
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			// The only error possible when reading from a string is EOF, but
+			// write out anything left over just in case.
+			if tokenizer.Err() != io.EOF { //nolint:errorlint
+				buf.Write(tokenizer.Raw())
+			}
+			break
+		}
+
+		// Raw must be copied before Token is called because the latter may
+		// modify the underlying buffer.
+		raw := append([]byte(nil), tokenizer.Raw()...)
+
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			buf.Write(raw)
+			continue
+		}
+
+		token := tokenizer.Token()
+
+		var changed bool
+		for i, attr := range token.Attr {
+			var val string
+			switch {
+			case attr.Key == "srcset":
+				val = resolveSrcSet(baseURL, attr.Val)
+			case urlAttrs[attr.Key]:
+				val = resolveURL(baseURL, attr.Val)
+			default:
+				continue
+			}
+
+			if val != attr.Val {
+				token.Attr[i].Val = val
+				changed = true
+			}
+		}
+
+		if changed {
+			buf.WriteString(token.String())
+		} else {
+			buf.Write(raw)
+		}
+	}
+
+	return buf.String()
+}
+
+// Resolves a single URL against baseURL. URLs that are already absolute,
+// fragment-only URLs that point within the same document, and URLs that can't
+// be parsed are returned unchanged.
+func resolveURL(baseURL *url.URL, val string) string {
+	trimmed := strings.TrimSpace(val)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return val
+	}
+
+	u, err := url.Parse(trimmed)
+	if err != nil || u.IsAbs() {
+		return val
+	}
+
+	return baseURL.ResolveReference(u).String()
+}
+
+// Resolves each candidate URL in a `srcset` attribute against baseURL,
+// preserving its descriptor (e.g. `2x` or `800w`).
+func resolveSrcSet(baseURL *url.URL, val string) string {
+	var changed bool
+
+	candidates := strings.Split(val, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) < 1 {
+			continue
+		}
+
+		if resolved := resolveURL(baseURL, fields[0]); resolved != fields[0] {
+			fields[0] = resolved
+			changed = true
+		}
+		candidates[i] = strings.Join(fields, " ")
+	}
+
+	// Avoid reformatting a srcset in which nothing changed.
+	if !changed {
+		return val
+	}
+
+	return strings.Join(candidates, ", ")
+}
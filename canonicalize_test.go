@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeURLs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"SampleContent", sampleContent, sampleContentCanonicalized},
+		{"AbsoluteUntouched", `<a href="https://example.com/foo">`, `<a href="https://example.com/foo">`},
+		{"FragmentUntouched", `<a href="#footnote-1">`, `<a href="#footnote-1">`},
+		{"MailtoUntouched", `<a href="mailto:brandur@example.com">`, `<a href="mailto:brandur@example.com">`},
+		{"EmptyUntouched", `<a href="">`, `<a href="">`},
+		{"NoURLAttrsUntouched", `<p class="note">text &amp; more</p>`, `<p class="note">text &amp; more</p>`},
+		{"RootRelative", `<a href="/about">`, `<a href="https://brandur.org/about">`},
+		{"SingleQuoted", `<a href='/about'>`, `<a href="https://brandur.org/about">`},
+		{"Unquoted", `<a href=/about>`, `<a href="https://brandur.org/about">`},
+		{"AttributesBeforeHref", `<a class="link"  title="About" href="/about">`, `<a class="link" title="About" href="https://brandur.org/about">`},
+		{"ProtocolRelative", `<img src="//cdn.example.com/a.jpg">`, `<img src="https://cdn.example.com/a.jpg">`},
+		{"PathRelative", `<img src="../photographs/a.jpg">`, `<img src="https://brandur.org/photographs/a.jpg">`},
+		{"Poster", `<video poster="/a.jpg">`, `<video poster="https://brandur.org/a.jpg">`},
+		{"Source", `<source src="/a.mp4">`, `<source src="https://brandur.org/a.mp4">`},
+		{"SelfClosing", `<img src="/a.jpg"/>`, `<img src="https://brandur.org/a.jpg"/>`},
+		{
+			"SrcSet",
+			`<img srcset="/a@2x.jpg 2x, https://example.com/a.jpg 1x">`,
+			`<img srcset="https://brandur.org/a@2x.jpg 2x, https://example.com/a.jpg 1x">`,
+		},
+		{
+			"SrcSetUntouched",
+			`<img srcset="https://example.com/a@2x.jpg 2x,https://example.com/a.jpg 1x">`,
+			`<img srcset="https://example.com/a@2x.jpg 2x,https://example.com/a.jpg 1x">`,
+		},
+		{
+			"AmpersandInQuery",
+			`<a href="/search?q=a&amp;page=2">`,
+			`<a href="https://brandur.org/search?q=a&amp;page=2">`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, canonicalizeURLs(tc.content, sampleBaseURL))
+		})
+	}
+}
+
+func TestResolveURL(t *testing.T) {
+	baseURL := mustParseURL("https://brandur.org/sequences/")
+
+	require.Equal(t, "https://brandur.org/sequences/030", resolveURL(baseURL, "030"))
+	require.Equal(t, "https://brandur.org/atoms", resolveURL(baseURL, "../atoms"))
+	require.Equal(t, "https://brandur.org/atoms", resolveURL(baseURL, "/atoms"))
+	require.Equal(t, "http://example.com/", resolveURL(baseURL, "http://example.com/"))
+}
+
+func mustParseURL(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Maximum size of a response body that'll be read. Boards are tiny, but feeds
+// can legitimately be a few megabytes, so this leaves plenty of room while
+// stopping a hostile or broken server from exhausting memory.
+const maxResponseSize = 10 * 1024 * 1024
+
+// ErrResponseTooLarge is returned when a response body exceeds
+// maxResponseSize.
+var ErrResponseTooLarge = xerrors.Errorf("response body larger than maximum of %d bytes", maxResponseSize)
+
+// httpRequester makes HTTP requests with its client, retrying failures
+// according to its policy. See requestWithRetries.
+type httpRequester struct {
+	Client      *http.Client
+	RetryPolicy retryPolicy
+
+	// Now gets the current time, which `Retry-After` dates are relative to.
+	// Defaults to time.Now if nil.
+	Now func() time.Time
+}
+
+// Builds an httpRequester with a client from newHTTPClient and the default
+// retry policy.
+func newHTTPRequester() *httpRequester {
+	return &httpRequester{
+		Client:      newHTTPClient(),
+		RetryPolicy: defaultRetryPolicy,
+		Now:         time.Now,
+	}
+}
+
+func (requester *httpRequester) now() time.Time {
+	if requester.Now == nil {
+		return time.Now()
+	}
+	return requester.Now()
+}
+
+// Builds an HTTP client with timeouts on each phase of a request, unlike
+// http.DefaultClient, which will wait forever on an unresponsive server.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.DialContext = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = 30 * time.Second
+	transport.TLSHandshakeTimeout = 10 * time.Second
+
+	return &http.Client{
+		Timeout:   60 * time.Second,
+		Transport: transport,
+	}
+}
+
+// Reads a response body up to maxResponseSize, returning ErrResponseTooLarge
+// if it's any larger.
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	if resp.ContentLength > maxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+
+	// Read one extra byte to tell a body of exactly the maximum size apart
+	// from one that's larger.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > maxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+
+	return body, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripperFunc allows a function to be used as an http.RoundTripper so
+// that tests can swap in a transport.
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// Builds a requester for tests that retries quickly.
+func newTestRequester(transport http.RoundTripper) *httpRequester {
+	return &httpRequester{
+		Client:      &http.Client{Transport: transport},
+		RetryPolicy: retryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxTotalWait: time.Second},
+	}
+}
+
+// closeTrackingBody is a response body that records whether it was closed.
+type closeTrackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *closeTrackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestNewHTTPRequester(t *testing.T) {
+	requester := newHTTPRequester()
+	require.NotNil(t, requester.Client)
+	require.Equal(t, defaultRetryPolicy, requester.RetryPolicy)
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	client := newHTTPClient()
+	require.Equal(t, 60*time.Second, client.Timeout)
+
+	transport, ok := client.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.NotNil(t, transport.DialContext)
+	require.Equal(t, 30*time.Second, transport.ResponseHeaderTimeout)
+	require.Equal(t, 10*time.Second, transport.TLSHandshakeTimeout)
+}
+
+func TestReadResponseBody(t *testing.T) {
+	newResponse := func(body string, contentLength int64) *http.Response {
+		return &http.Response{
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: contentLength,
+		}
+	}
+
+	t.Run("Okay", func(t *testing.T) {
+		body, err := readResponseBody(newResponse("hello", 5))
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(body))
+	})
+
+	t.Run("ExactlyMaximum", func(t *testing.T) {
+		body, err := readResponseBody(newResponse(strings.Repeat("a", maxResponseSize), -1))
+		require.NoError(t, err)
+		require.Len(t, body, maxResponseSize)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		_, err := readResponseBody(newResponse(strings.Repeat("a", maxResponseSize+1), -1))
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+	})
+
+	t.Run("ContentLengthTooLarge", func(t *testing.T) {
+		_, err := readResponseBody(newResponse("", maxResponseSize+1))
+		require.ErrorIs(t, err, ErrResponseTooLarge)
+	})
+}
+
+func TestDoRequestClosesBody(t *testing.T) {
+	var bodies []*closeTrackingBody
+	requester := newTestRequester(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		body := &closeTrackingBody{Reader: bytes.NewReader([]byte("ok"))}
+		bodies = append(bodies, body)
+		return &http.Response{Body: body, Header: http.Header{}, StatusCode: http.StatusOK}, nil
+	}))
+
+	resp, err := requester.doRequest(context.Background(), http.MethodGet, "https://example.com", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(resp.Body))
+	require.Len(t, bodies, 1)
+	require.True(t, bodies[0].closed)
+}
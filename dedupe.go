@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Removes duplicate entries, like the same post appearing in more than one
+// feed or a syndicated copy of it. Entries are duplicates if they have the
+// same ID or the same normalized link. Of a set of duplicates, the most
+// recently updated one is kept, or the first one seen on a tie.
+//
+// The order of entries is otherwise preserved.
+func dedupeEntries(entries []*Entry) []*Entry {
+	var deduped []*Entry
+
+	// Indexes into deduped by ID and normalized link.
+	byID := make(map[string]int)
+	byLink := make(map[string]int)
+
+	for _, entry := range entries {
+		id := strings.TrimSpace(entry.ID)
+		link := normalizedEntryLink(entry)
+
+		i, ok := byID[id]
+		if !ok || id == "" {
+			i, ok = byLink[link]
+			ok = ok && link != ""
+		}
+
+		if !ok {
+			i = len(deduped)
+			deduped = append(deduped, entry)
+		} else {
+			logger.Infof("Found duplicate of entry %q (ID: %q, link: %q)", entry.Title, id, link)
+
+			if entryUpdated(entry).After(entryUpdated(deduped[i])) {
+				deduped[i] = entry
+			}
+		}
+
+		// Also register the keys of a duplicate so that entries matching it
+		// by a key the first one seen didn't have are caught too.
+		if id != "" {
+			if _, ok := byID[id]; !ok {
+				byID[id] = i
+			}
+		}
+		if link != "" {
+			if _, ok := byLink[link]; !ok {
+				byLink[link] = i
+			}
+		}
+	}
+
+	return deduped
+}
+
+// Gets an entry's link resolved against its base URL and normalized so that
+// trivially different forms of the same URL compare equal. The scheme and
+// host are lowercased, and default ports, fragments, and trailing slashes are
+// removed. Returns an empty string if the entry has no link.
+func normalizedEntryLink(entry *Entry) string {
+	if entry.Link == nil || strings.TrimSpace(entry.Link.Href) == "" {
+		return ""
+	}
+
+	href := strings.TrimSpace(entry.Link.Href)
+	if entry.BaseURL != nil {
+		href = resolveURL(entry.BaseURL, href)
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	return u.String()
+}
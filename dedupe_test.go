@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupeEntries(t *testing.T) {
+	older := time.Date(2022, 11, 12, 9, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	t.Run("NoDuplicates", func(t *testing.T) {
+		entries := []*Entry{
+			{ID: "1", Link: &Link{Href: "https://example.com/1"}},
+			{ID: "2", Link: &Link{Href: "https://example.com/2"}},
+			{},
+			{},
+		}
+		require.Equal(t, entries, dedupeEntries(entries))
+	})
+
+	t.Run("ByID", func(t *testing.T) {
+		entries := []*Entry{
+			{ID: "1", Title: "Old", Updated: older},
+			{ID: "2"},
+			{ID: "1", Title: "New", Updated: newer},
+		}
+		require.Equal(t, []*Entry{entries[2], entries[1]}, dedupeEntries(entries))
+	})
+
+	t.Run("ByLink", func(t *testing.T) {
+		entries := []*Entry{
+			{ID: "1", Link: &Link{Href: "https://example.com/1"}, Updated: newer},
+			{ID: "copy-of-1", Link: &Link{Href: "https://EXAMPLE.com:443/1/#copy"}, Updated: older},
+		}
+		require.Equal(t, entries[:1], dedupeEntries(entries))
+	})
+
+	t.Run("UpdatedFallsBackToPublished", func(t *testing.T) {
+		entries := []*Entry{
+			{ID: "1", Updated: older},
+			{ID: "1", Published: newer},
+		}
+		require.Equal(t, entries[1:], dedupeEntries(entries))
+	})
+
+	t.Run("TieKeepsFirst", func(t *testing.T) {
+		entries := []*Entry{
+			{ID: "1", Updated: older},
+			{ID: "1", Updated: older},
+		}
+		require.Equal(t, entries[:1], dedupeEntries(entries))
+	})
+
+	t.Run("Transitive", func(t *testing.T) {
+		// The second matches the first by ID, and the third matches the
+		// second by a link that the first didn't have.
+		entries := []*Entry{
+			{ID: "1", Updated: older},
+			{ID: "1", Link: &Link{Href: "https://example.com/1"}, Updated: older},
+			{ID: "copy-of-1", Link: &Link{Href: "https://example.com/1"}, Updated: newer},
+		}
+		require.Equal(t, entries[2:], dedupeEntries(entries))
+	})
+}
+
+func TestNormalizedEntryLink(t *testing.T) {
+	baseURL, err := url.Parse("https://example.com/sequences/")
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		href     string
+		expected string
+	}{
+		{"", ""},
+		{"https://example.com/sequences/030", "https://example.com/sequences/030"},
+		{"HTTPS://Example.COM/sequences/030/", "https://example.com/sequences/030"},
+		{"https://example.com:443/sequences/030#photo", "https://example.com/sequences/030"},
+		{"http://example.com:80/sequences/030", "http://example.com/sequences/030"},
+		{"http://example.com:8080/sequences/030", "http://example.com:8080/sequences/030"},
+		{"030", "https://example.com/sequences/030"},
+		{"/sequences/030?ref=feed", "https://example.com/sequences/030?ref=feed"},
+	} {
+		require.Equal(t, tt.expected, normalizedEntryLink(&Entry{Link: &Link{Href: tt.href}, BaseURL: baseURL}), tt.href)
+	}
+
+	require.Empty(t, normalizedEntryLink(&Entry{}))
+}
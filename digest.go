@@ -0,0 +1,121 @@
+package main
+
+import (
+	"html"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/xerrors"
+)
+
+// Maximum size in bytes of each entry's excerpt in a digest board.
+const digestExcerptSize = 140
+
+// Format of each entry's date in a digest board.
+const digestDateFormat = "Jan 2, 2006"
+
+// digestEntry is the data for a single entry in the digest layout.
+type digestEntry struct {
+	Date    string
+	Excerpt string
+	Title   string
+	URL     string
+}
+
+// Renders a digest board listing up to size of the given entries, which
+// should be sorted newest first. If the board doesn't fit within
+// maxBoardSize, entries are dropped from the end until it does. Returns the
+// board along with the number of entries that made it in.
+//
+// The board is timestamped with the newest entry's publish time.
+func renderDigestBoard(title string, entries []*Entry, size int) (*renderedBoard, int, error) {
+	if len(entries) < 1 {
+		return nil, 0, xerrors.Errorf("no entries for digest")
+	}
+
+	digestEntries := make([]*digestEntry, minInt(size, len(entries)))
+	for i := range digestEntries {
+		digestEntry, err := newDigestEntry(entries[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		digestEntries[i] = digestEntry
+	}
+
+	timestamp := entries[0].Published
+
+	for n := len(digestEntries); n > 0; n-- {
+		rendered, err := renderLayoutData("digest.tmpl.html", map[string]any{
+			"Entries":   digestEntries[:n],
+			"Timestamp": timestampTag(timestamp),
+			"Title":     html.EscapeString(title),
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		rendered = minimizeWhitespace(rendered)
+
+		if len(rendered) <= maxBoardSize {
+			return &renderedBoard{Content: rendered, Timestamp: timestamp}, n, nil
+		}
+
+		logger.Infof("Digest board is %d bytes with %d entries (max: %d bytes)", len(rendered), n, maxBoardSize)
+	}
+
+	return nil, 0, xerrors.Errorf("%w: digest doesn't fit even with a single entry", ErrBoardTooLarge)
+}
+
+// Produces the digest data for an entry. Its excerpt is the entry's summary,
+// or otherwise the start of the text of its content.
+func newDigestEntry(entry *Entry) (*digestEntry, error) {
+	excerpt := entry.Summary
+	if excerpt == "" {
+		text, err := htmlText(stripMedia(entryContent(entry)))
+		if err != nil {
+			return nil, err
+		}
+		excerpt = text
+	}
+
+	var entryURL string
+	if entry.Link != nil {
+		entryURL = entry.Link.Href
+		if entry.BaseURL != nil {
+			entryURL = resolveURL(entry.BaseURL, entryURL)
+		}
+	}
+
+	return &digestEntry{
+		Date:    entry.Published.Format(digestDateFormat),
+		Excerpt: html.EscapeString(truncateExcerpt(excerpt, digestExcerptSize)),
+		Title:   html.EscapeString(entry.Title),
+		URL:     html.EscapeString(entryURL),
+	}, nil
+}
+
+// Gets the text of an HTML fragment with whitespace collapsed.
+func htmlText(content string) (string, error) {
+	container := &nethtml.Node{Type: nethtml.ElementNode, Data: "body", DataAtom: atom.Body}
+
+	nodes, err := nethtml.ParseFragment(strings.NewReader(content), container)
+	if err != nil {
+		return "", xerrors.Errorf("error parsing HTML: %w", err)
+	}
+
+	for _, node := range nodes {
+		container.AppendChild(node)
+	}
+
+	return microformatText(container), nil
+}
+
+// Truncates text to fit within size on a word boundary, adding an ellipsis if
+// anything was cut.
+func truncateExcerpt(text string, size int) string {
+	if len(html.EscapeString(text)) <= size {
+		return text
+	}
+	return truncateText(text, size)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDigestBoard(t *testing.T) {
+	newEntries := func(n int, title string) []*Entry {
+		entries := make([]*Entry, n)
+		for i := range entries {
+			entries[i] = &Entry{
+				Title:     fmt.Sprintf("%s %d", title, i),
+				Content:   &EntryContent{Content: "<p>Some content.</p>"},
+				Published: time.Date(2022, 11, 9-i, 10, 11, 12, 0, time.UTC),
+				Link:      &Link{Href: fmt.Sprintf("https://example.com/%d", i)},
+			}
+		}
+		return entries
+	}
+
+	t.Run("AllFit", func(t *testing.T) {
+		entries := newEntries(3, "Entry")
+
+		board, n, err := renderDigestBoard("Latest", entries, 5)
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+		require.Equal(t, entries[0].Published, board.Timestamp)
+		require.True(t, strings.HasPrefix(board.Content, `<time datetime="2022-11-09T10:11:12Z">`))
+		require.Contains(t, board.Content, "<h1>Latest</h1>")
+		require.Contains(t, board.Content, `<h2><a href="https://example.com/2">Entry 2</a></h2>`)
+		require.Contains(t, board.Content, "<small>Nov 7, 2022</small>")
+		require.Contains(t, board.Content, "<p>Some content.</p>")
+	})
+
+	t.Run("Escapes", func(t *testing.T) {
+		entries := []*Entry{{
+			Title:     "Why <script>alert(1)</script> matters & more",
+			Summary:   `Use <b> & "quotes"`,
+			Published: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+			Link:      &Link{Href: `https://x.com/a"onmouseover="alert(1)`},
+		}}
+
+		board, _, err := renderDigestBoard("<i>Latest</i>", entries, 5)
+		require.NoError(t, err)
+		require.Contains(t, board.Content, "<h1>&lt;i&gt;Latest&lt;/i&gt;</h1>")
+		require.Contains(t, board.Content,
+			`<h2><a href="https://x.com/a&#34;onmouseover=&#34;alert(1)">Why &lt;script&gt;alert(1)&lt;/script&gt; matters &amp; more</a></h2>`)
+		require.Contains(t, board.Content, "<p>Use &lt;b&gt; &amp; &#34;quotes&#34;</p>")
+		require.NotContains(t, board.Content, "<script>")
+		require.NotContains(t, board.Content, `"onmouseover`)
+	})
+
+	t.Run("LimitedBySize", func(t *testing.T) {
+		_, n, err := renderDigestBoard("Latest", newEntries(3, "Entry"), 2)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("Shrinks", func(t *testing.T) {
+		board, n, err := renderDigestBoard("Latest", newEntries(10, strings.Repeat("Long title ", 20)), 10)
+		require.NoError(t, err)
+		require.Less(t, n, 10)
+		require.Greater(t, n, 0)
+		require.LessOrEqual(t, len(board.Content), maxBoardSize)
+	})
+
+	t.Run("NothingFits", func(t *testing.T) {
+		_, _, err := renderDigestBoard("Latest", newEntries(1, strings.Repeat("Long title ", 300)), 1)
+		require.ErrorIs(t, err, ErrBoardTooLarge)
+	})
+
+	t.Run("NoEntries", func(t *testing.T) {
+		_, _, err := renderDigestBoard("Latest", nil, 5)
+		require.EqualError(t, err, "no entries for digest")
+	})
+}
+
+func TestNewDigestEntry(t *testing.T) {
+	published := time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC)
+
+	t.Run("ContentExcerpt", func(t *testing.T) {
+		digestEntry, err := newDigestEntry(&Entry{
+			Title:     "A title",
+			Content:   &EntryContent{Content: "<p>" + strings.Repeat("word ", 50) + "</p>"},
+			Published: published,
+			Link:      &Link{Href: "/sequences/030"},
+			BaseURL:   mustParseURL("https://example.com/sequences"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Nov 9, 2022", digestEntry.Date)
+		require.Equal(t, "A title", digestEntry.Title)
+		require.Equal(t, "https://example.com/sequences/030", digestEntry.URL)
+		require.LessOrEqual(t, len(digestEntry.Excerpt), digestExcerptSize)
+		require.True(t, strings.HasSuffix(digestEntry.Excerpt, "word…"))
+	})
+
+	t.Run("SummaryExcerpt", func(t *testing.T) {
+		digestEntry, err := newDigestEntry(&Entry{
+			Summary:   "A summary.",
+			Content:   &EntryContent{Content: "<p>Content.</p>"},
+			Published: published,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "A summary.", digestEntry.Excerpt)
+		require.Empty(t, digestEntry.URL)
+	})
+}
+
+func TestHTMLText(t *testing.T) {
+	text, err := htmlText("<p>Hello,\n  <em>world</em>.</p>\n<p><img src=\"a.jpg\" alt=\"A photo\"></p><style>p {}</style>")
+	require.NoError(t, err)
+	require.Equal(t, "Hello, world. A photo", text)
+}
+
+func TestTruncateExcerpt(t *testing.T) {
+	require.Equal(t, "short", truncateExcerpt("short", 10))
+	require.Equal(t, "a bit…", truncateExcerpt("a bit longer", 10))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// emptyFeedPolicy is what to do when there are no entries across all feeds.
+type emptyFeedPolicy string
+
+const (
+	// emptyFeedPolicyFail fails the run so that the problem is noticed.
+	emptyFeedPolicyFail emptyFeedPolicy = "fail"
+
+	// emptyFeedPolicyKeep leaves whatever board is currently published in
+	// place. This is the default.
+	emptyFeedPolicyKeep emptyFeedPolicy = "keep"
+
+	// emptyFeedPolicyPlaceholder publishes a configured placeholder board.
+	emptyFeedPolicyPlaceholder emptyFeedPolicy = "placeholder"
+)
+
+// ErrNoEntries is returned by a run with emptyFeedPolicyFail when there are no
+// entries across all feeds.
+var ErrNoEntries = xerrors.New("no entries in any feed")
+
+// UnmarshalText parses a policy from configuration, rejecting unknown ones.
+func (p *emptyFeedPolicy) UnmarshalText(text []byte) error {
+	switch policy := emptyFeedPolicy(text); policy {
+	case emptyFeedPolicyFail, emptyFeedPolicyKeep, emptyFeedPolicyPlaceholder:
+		*p = policy
+		return nil
+	}
+
+	return xerrors.Errorf("unknown empty feed policy %q (should be one of: %s, %s, %s)",
+		string(text), emptyFeedPolicyFail, emptyFeedPolicyKeep, emptyFeedPolicyPlaceholder)
+}
+
+// Builds the entry for a placeholder board. It's timestamped now so that it
+// supersedes whatever board was published last.
+func placeholderEntry(title, content string, now time.Time) *Entry {
+	return &Entry{
+		Title:     title,
+		Content:   &EntryContent{Content: content, Type: "html"},
+		Published: now.UTC().Truncate(time.Second),
+		Updated:   now.UTC().Truncate(time.Second),
+	}
+}
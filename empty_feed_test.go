@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmptyFeedPolicyUnmarshalText(t *testing.T) {
+	for _, policy := range []emptyFeedPolicy{emptyFeedPolicyFail, emptyFeedPolicyKeep, emptyFeedPolicyPlaceholder} {
+		var parsed emptyFeedPolicy
+		require.NoError(t, parsed.UnmarshalText([]byte(policy)))
+		require.Equal(t, policy, parsed)
+	}
+
+	var parsed emptyFeedPolicy
+	require.EqualError(t, parsed.UnmarshalText([]byte("ignore")),
+		`unknown empty feed policy "ignore" (should be one of: fail, keep, placeholder)`)
+}
+
+func TestEmptyFeedPolicyEnv(t *testing.T) {
+	type config struct {
+		EmptyFeedPolicy emptyFeedPolicy `env:"EMPTY_FEED_POLICY" envDefault:"keep"`
+	}
+
+	{
+		var c config
+		require.NoError(t, env.Parse(&c))
+		require.Equal(t, emptyFeedPolicyKeep, c.EmptyFeedPolicy)
+	}
+
+	{
+		t.Setenv("EMPTY_FEED_POLICY", "placeholder")
+		var c config
+		require.NoError(t, env.Parse(&c))
+		require.Equal(t, emptyFeedPolicyPlaceholder, c.EmptyFeedPolicy)
+	}
+
+	{
+		t.Setenv("EMPTY_FEED_POLICY", "ignore")
+		var c config
+		require.ErrorContains(t, env.Parse(&c), "unknown empty feed policy")
+	}
+}
+
+func TestPlaceholderEntry(t *testing.T) {
+	now := time.Date(2022, 11, 9, 10, 11, 12, 345, time.FixedZone("PST", -8*60*60))
+
+	entry := placeholderEntry("a title", "<p>placeholder</p>", now)
+	require.Equal(t, "a title", entry.Title)
+	require.Equal(t, "<p>placeholder</p>", entry.Content.Content)
+	require.Equal(t, time.Date(2022, 11, 9, 18, 11, 12, 0, time.UTC), entry.Published)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// feedFormat is a syndication format that a feed can be published in.
+type feedFormat string
+
+const (
+	feedFormatAtom     feedFormat = "atom"
+	feedFormatHFeed    feedFormat = "h_feed"
+	feedFormatJSONFeed feedFormat = "json_feed"
+	feedFormatRSS      feedFormat = "rss"
+)
+
+// Detects the format of a feed. JSON Feeds and HTML pages (to be parsed for
+// an h-feed) are identified by content type, or failing that, by a `version`
+// field in a JSON object and a leading doctype or `<html>` respectively.
+// Otherwise the feed is assumed to be XML, and Atom or RSS is identified from
+// the name of its root element.
+func detectFeedFormat(data []byte, contentType string) (feedFormat, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/feed+json":
+		return feedFormatJSONFeed, nil
+	case "text/html":
+		return feedFormatHFeed, nil
+	}
+
+	if looksLikeHTML(data) {
+		return feedFormatHFeed, nil
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var versioned struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(trimmed, &versioned); err != nil {
+			return "", xerrors.Errorf("error unmarshaling JSON feed: %w", err)
+		}
+
+		if strings.HasPrefix(versioned.Version, jsonFeedVersionPrefix) {
+			return feedFormatJSONFeed, nil
+		}
+
+		return "", xerrors.Errorf("JSON feed has unknown version: %q", versioned.Version)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if xerrors.Is(err, io.EOF) {
+				return "", xerrors.Errorf("no root element found in feed")
+			}
+			return "", xerrors.Errorf("error reading XML feed: %w", err)
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			switch start.Name.Local {
+			case "feed":
+				return feedFormatAtom, nil
+			case "html":
+				return feedFormatHFeed, nil
+			case "rss":
+				return feedFormatRSS, nil
+			}
+
+			return "", xerrors.Errorf("unknown feed root element: <%s>", start.Name.Local)
+		}
+	}
+}
+
+// Parses a feed in any supported format. Regardless of format, the result is
+// an Atom Feed so that entries can go through the same pipeline. feedURL is
+// where the feed was fetched from, which URLs in an h-feed are resolved
+// against.
+func parseFeed(data []byte, contentType, feedURL string) (*Feed, error) {
+	format, err := detectFeedFormat(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case feedFormatAtom:
+		var feed Feed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return nil, xerrors.Errorf("error unmarshaling Atom feed: %w", err)
+		}
+		return &feed, nil
+
+	case feedFormatHFeed:
+		pageURL, err := url.Parse(feedURL)
+		if err != nil {
+			return nil, xerrors.Errorf("error parsing page URL %q: %w", feedURL, err)
+		}
+		return parseHFeed(data, pageURL)
+
+	case feedFormatJSONFeed:
+		var jsonFeed JSONFeed
+		if err := json.Unmarshal(data, &jsonFeed); err != nil {
+			return nil, xerrors.Errorf("error unmarshaling JSON Feed: %w", err)
+		}
+		return jsonFeed.ToFeed()
+
+	case feedFormatRSS:
+		var rss RSS
+		if err := xml.Unmarshal(data, &rss); err != nil {
+			return nil, xerrors.Errorf("error unmarshaling RSS feed: %w", err)
+		}
+		return rss.ToFeed()
+	}
+
+	return nil, xerrors.Errorf("unhandled feed format: %q", format)
+}
+
+// Whether data starts with an HTML doctype or `<html>` tag, neither of which
+// an XML decoder is guaranteed to make it past.
+func looksLikeHTML(data []byte) bool {
+	prefix := bytes.ToLower(bytes.TrimSpace(data))
+	if len(prefix) > 20 {
+		prefix = prefix[0:20]
+	}
+
+	return bytes.HasPrefix(prefix, []byte("<!doctype html")) || bytes.HasPrefix(prefix, []byte("<html"))
+}
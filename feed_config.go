@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// The layout used for entries of feeds that don't configure one.
+const defaultLayout = "sequences.tmpl.html"
+
+// feedConfig is the configuration for a single feed.
+type feedConfig struct {
+	// URL is the feed's URL. It may be Atom, RSS, JSON Feed, or an HTML page
+	// marked up with h-feed.
+	URL string `json:"url"`
+
+	// BaseURL is the URL that relative URLs in the feed's entries are
+	// resolved against. Takes precedence over CANONICAL_URL, and is derived
+	// from the feed if neither is set.
+	BaseURL string `json:"base_url"`
+
+	// Filter decides which of the feed's entries are eligible for publishing.
+	Filter *entryFilter `json:"filter"`
+
+	// Layout is the layout that the feed's entries are rendered with. Either
+	// the name of one of the built-in layouts in `layouts/`, or the path of a
+	// template file, which is relative to the configuration file.
+	Layout string `json:"layout"`
+
+	// Priority is added to the priority of the feed's entries under
+	// entrySelectionCategoryPriority, so that entries from one feed can be
+	// favored over another's. It's an error to set it under any other
+	// selection, or for a digest, since they'd ignore it.
+	Priority int `json:"priority"`
+
+	// parsed form of BaseURL
+	baseURL *url.URL
+}
+
+// feedsConfigFile is the structure of the file at FEEDS_CONFIG_PATH.
+type feedsConfigFile struct {
+	Feeds []*feedConfig `json:"feeds"`
+}
+
+// Gets the configuration of each feed, from the file at FEEDS_CONFIG_PATH if
+// it's set, or otherwise from ATOM_FEED_URL and FEED_FILTERS.
+func loadFeedConfigs(config *Config) ([]*feedConfig, error) {
+	switch {
+	case config.FeedsConfigPath != "" && config.AtomFeedURL != "":
+		return nil, xerrors.Errorf("only one of ATOM_FEED_URL and FEEDS_CONFIG_PATH should be set")
+
+	case config.FeedsConfigPath != "" && config.FeedFilters != nil:
+		return nil, xerrors.Errorf("FEED_FILTERS can't be used with FEEDS_CONFIG_PATH (configure filters in the file instead)")
+
+	case config.FeedsConfigPath != "":
+		return readFeedConfigs(config.FeedsConfigPath)
+
+	case config.AtomFeedURL != "":
+		return feedConfigsFromURLs(config.AtomFeedURL, config.FeedFilters)
+	}
+
+	return nil, xerrors.Errorf("one of ATOM_FEED_URL or FEEDS_CONFIG_PATH is required")
+}
+
+// Reads feed configuration from a JSON file.
+func readFeedConfigs(path string) ([]*feedConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("error reading feeds config: %w", err)
+	}
+
+	// Unknown fields are disallowed so that a typo doesn't go unnoticed.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var file feedsConfigFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, xerrors.Errorf("error parsing feeds config %q: %w", path, err)
+	}
+
+	if len(file.Feeds) < 1 {
+		return nil, xerrors.Errorf("feeds config %q has no feeds", path)
+	}
+
+	seen := make(map[string]bool)
+
+	for i, feed := range file.Feeds {
+		if feed == nil || feed.URL == "" {
+			return nil, xerrors.Errorf("feed %d in feeds config %q has no URL", i, path)
+		}
+
+		if seen[feed.URL] {
+			return nil, xerrors.Errorf("feed %q is configured more than once", feed.URL)
+		}
+		seen[feed.URL] = true
+
+		if feed.BaseURL != "" {
+			feed.baseURL, err = url.Parse(feed.BaseURL)
+			if err != nil {
+				return nil, xerrors.Errorf("error parsing base URL of feed %q: %w", feed.URL, err)
+			}
+
+			if !feed.baseURL.IsAbs() {
+				return nil, xerrors.Errorf("base URL of feed %q should be an absolute URL, but was %q",
+					feed.URL, feed.BaseURL)
+			}
+		}
+
+		if feed.Layout != "" {
+			if !isBuiltinLayout(feed.Layout) {
+				feed.Layout = filepath.Join(filepath.Dir(path), feed.Layout)
+			}
+
+			// Checked up front so that a broken template isn't only noticed
+			// once one of the feed's entries is selected.
+			if _, err := loadLayout(feed.Layout); err != nil {
+				return nil, xerrors.Errorf("error loading layout of feed %q: %w", feed.URL, err)
+			}
+		}
+	}
+
+	return file.Feeds, nil
+}
+
+// Builds feed configuration from a comma-separated list of feed URLs and
+// filters keyed by feed URL.
+func feedConfigsFromURLs(feedURLs string, filters feedFilters) ([]*feedConfig, error) {
+	var feeds []*feedConfig
+	for _, feedURL := range strings.Split(feedURLs, ",") {
+		feeds = append(feeds, &feedConfig{URL: feedURL, Filter: filters[feedURL]})
+	}
+
+	for filterURL := range filters {
+		if !hasFeedURL(feeds, filterURL) {
+			return nil, xerrors.Errorf("FEED_FILTERS has filters for %q, which isn't in ATOM_FEED_URL", filterURL)
+		}
+	}
+
+	return feeds, nil
+}
+
+func hasFeedURL(feeds []*feedConfig, feedURL string) bool {
+	for _, feed := range feeds {
+		if feed.URL == feedURL {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Whether layout names one of the layouts built into the program rather than
+// a template file.
+func isBuiltinLayout(layout string) bool {
+	if strings.ContainsRune(layout, '/') || strings.ContainsRune(layout, filepath.Separator) {
+		return false
+	}
+
+	_, err := fs.Stat(layoutsFS, "layouts/"+layout)
+	return err == nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFeedConfigs(t *testing.T) {
+	t.Run("FromURLs", func(t *testing.T) {
+		filter := &entryFilter{IncludeCategories: []string{"spring"}}
+
+		feeds, err := loadFeedConfigs(&Config{
+			AtomFeedURL: "https://example.com/a.atom,https://example.com/b.atom",
+			FeedFilters: feedFilters{"https://example.com/b.atom": filter},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []*feedConfig{
+			{URL: "https://example.com/a.atom"},
+			{URL: "https://example.com/b.atom", Filter: filter},
+		}, feeds)
+	})
+
+	t.Run("FromURLsUnknownFilter", func(t *testing.T) {
+		_, err := loadFeedConfigs(&Config{
+			AtomFeedURL: "https://example.com/a.atom",
+			FeedFilters: feedFilters{"https://example.com/b.atom": {}},
+		})
+		require.EqualError(t, err, `FEED_FILTERS has filters for "https://example.com/b.atom", which isn't in ATOM_FEED_URL`)
+	})
+
+	t.Run("FromFile", func(t *testing.T) {
+		path := writeFeedsConfigFile(t, `{"feeds": [{"url": "https://example.com/a.atom"}]}`)
+
+		feeds, err := loadFeedConfigs(&Config{FeedsConfigPath: path})
+		require.NoError(t, err)
+		require.Equal(t, []*feedConfig{{URL: "https://example.com/a.atom"}}, feeds)
+	})
+
+	t.Run("Both", func(t *testing.T) {
+		_, err := loadFeedConfigs(&Config{AtomFeedURL: "https://example.com/a.atom", FeedsConfigPath: "feeds.json"})
+		require.EqualError(t, err, "only one of ATOM_FEED_URL and FEEDS_CONFIG_PATH should be set")
+	})
+
+	t.Run("FileWithFilters", func(t *testing.T) {
+		_, err := loadFeedConfigs(&Config{FeedsConfigPath: "feeds.json", FeedFilters: feedFilters{}})
+		require.ErrorContains(t, err, "FEED_FILTERS can't be used with FEEDS_CONFIG_PATH")
+	})
+
+	t.Run("Neither", func(t *testing.T) {
+		_, err := loadFeedConfigs(&Config{})
+		require.EqualError(t, err, "one of ATOM_FEED_URL or FEEDS_CONFIG_PATH is required")
+	})
+}
+
+func TestReadFeedConfigs(t *testing.T) {
+	t.Run("AllOptions", func(t *testing.T) {
+		path := writeFeedsConfigFile(t, `{"feeds": [
+			{
+				"url": "https://example.com/atoms.atom",
+				"base_url": "https://example.org/",
+				"filter": {"exclude_titles": ["^Draft"]},
+				"layout": "atoms.tmpl.html",
+				"priority": 5
+			},
+			{
+				"url": "https://example.com/sequences.atom",
+				"layout": "custom.tmpl.html"
+			}
+		]}`)
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "custom.tmpl.html"),
+			[]byte("{{.Timestamp}}{{.Content}}"), 0o600))
+
+		feeds, err := readFeedConfigs(path)
+		require.NoError(t, err)
+		require.Len(t, feeds, 2)
+
+		require.Equal(t, "https://example.org/", feeds[0].baseURL.String())
+		require.Len(t, feeds[0].Filter.ExcludeTitles, 1)
+		require.Equal(t, "atoms.tmpl.html", feeds[0].Layout)
+		require.Equal(t, 5, feeds[0].Priority)
+
+		// Relative to the config file.
+		require.Equal(t, filepath.Join(filepath.Dir(path), "custom.tmpl.html"), feeds[1].Layout)
+		require.Nil(t, feeds[1].baseURL)
+	})
+
+	for _, tt := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"Malformed", `{"feeds": [`, "error parsing feeds config"},
+		{"UnknownField", `{"feeds": [{"url": "https://example.com/a.atom", "prority": 1}]}`, `unknown field "prority"`},
+		{"NoFeeds", `{"feeds": []}`, "has no feeds"},
+		{"NoURL", `{"feeds": [{"layout": "atoms.tmpl.html"}]}`, "feed 0 in feeds config"},
+		{
+			"Duplicate",
+			`{"feeds": [{"url": "https://example.com/a.atom"}, {"url": "https://example.com/a.atom"}]}`,
+			`feed "https://example.com/a.atom" is configured more than once`,
+		},
+		{
+			"RelativeBaseURL",
+			`{"feeds": [{"url": "https://example.com/a.atom", "base_url": "/blog"}]}`,
+			"should be an absolute URL",
+		},
+		{
+			"MissingLayout",
+			`{"feeds": [{"url": "https://example.com/a.atom", "layout": "missing.tmpl.html"}]}`,
+			`error loading layout of feed "https://example.com/a.atom"`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readFeedConfigs(writeFeedsConfigFile(t, tt.config))
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	t.Run("MissingFile", func(t *testing.T) {
+		_, err := readFeedConfigs(filepath.Join(t.TempDir(), "missing.json"))
+		require.ErrorContains(t, err, "error reading feeds config")
+	})
+}
+
+func TestIsBuiltinLayout(t *testing.T) {
+	require.True(t, isBuiltinLayout("atoms.tmpl.html"))
+	require.True(t, isBuiltinLayout("sequences.tmpl.html"))
+	require.False(t, isBuiltinLayout("missing.tmpl.html"))
+	require.False(t, isBuiltinLayout("layouts/sequences.tmpl.html"))
+}
+
+func writeFeedsConfigFile(t *testing.T, config string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "feeds.json")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+	return path
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const sampleAtom = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xml:lang="en-US" xmlns="http://www.w3.org/2005/Atom">
+	<title>Sequences</title>
+	<entry>
+		<title>Mount Rainier</title>
+		<content type="html"><![CDATA[<p>Full content.</p>]]></content>
+		<published>2022-11-09T10:11:12Z</published>
+		<id>tag:example.com,2022:sequences/030</id>
+	</entry>
+</feed>`
+
+func TestDetectFeedFormat(t *testing.T) {
+	{
+		format, err := detectFeedFormat([]byte(sampleAtom), "")
+		require.NoError(t, err)
+		require.Equal(t, feedFormatAtom, format)
+	}
+
+	{
+		format, err := detectFeedFormat([]byte(sampleRSS), "")
+		require.NoError(t, err)
+		require.Equal(t, feedFormatRSS, format)
+	}
+
+	{
+		format, err := detectFeedFormat([]byte(sampleJSONFeed), "")
+		require.NoError(t, err)
+		require.Equal(t, feedFormatJSONFeed, format)
+	}
+
+	{
+		format, err := detectFeedFormat([]byte(sampleJSONFeed), "application/feed+json; charset=utf-8")
+		require.NoError(t, err)
+		require.Equal(t, feedFormatJSONFeed, format)
+	}
+
+	{
+		_, err := detectFeedFormat([]byte(`{"version": "2"}`), "application/json")
+		require.EqualError(t, err, `JSON feed has unknown version: "2"`)
+	}
+
+	{
+		format, err := detectFeedFormat([]byte(sampleHFeed), "")
+		require.NoError(t, err)
+		require.Equal(t, feedFormatHFeed, format)
+	}
+
+	{
+		format, err := detectFeedFormat([]byte(`<p>not even a full page</p>`), "text/html; charset=utf-8")
+		require.NoError(t, err)
+		require.Equal(t, feedFormatHFeed, format)
+	}
+
+	{
+		_, err := detectFeedFormat([]byte(`<opml version="2.0"></opml>`), "")
+		require.EqualError(t, err, "unknown feed root element: <opml>")
+	}
+
+	{
+		_, err := detectFeedFormat([]byte(``), "")
+		require.EqualError(t, err, "no root element found in feed")
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	t.Run("Atom", func(t *testing.T) {
+		feed, err := parseFeed([]byte(sampleAtom), "", "https://example.com/feed")
+		require.NoError(t, err)
+		require.Len(t, feed.Entries, 1)
+		require.Equal(t, "Mount Rainier", feed.Entries[0].Title)
+	})
+
+	t.Run("HFeed", func(t *testing.T) {
+		feed, err := parseFeed([]byte(sampleHFeed), "text/html", "https://example.com/feed")
+		require.NoError(t, err)
+		require.Len(t, feed.Entries, 2)
+		require.Equal(t, "Mount Rainier", feed.Entries[0].Title)
+	})
+
+	t.Run("JSONFeed", func(t *testing.T) {
+		feed, err := parseFeed([]byte(sampleJSONFeed), "application/feed+json", "https://example.com/feed")
+		require.NoError(t, err)
+		require.Len(t, feed.Entries, 2)
+		require.Equal(t, "Mount Rainier", feed.Entries[0].Title)
+	})
+
+	t.Run("RSS", func(t *testing.T) {
+		feed, err := parseFeed([]byte(sampleRSS), "", "https://example.com/feed")
+		require.NoError(t, err)
+		require.Len(t, feed.Entries, 2)
+		require.Equal(t, "Mount Rainier", feed.Entries[0].Title)
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// entryFilter decides which of a feed's entries are eligible for publishing.
+// An entry passes if it matches every include rule that's set and none of
+// the exclude rules. Category terms and author names are compared
+// case-insensitively.
+type entryFilter struct {
+	ExcludeAuthors    []string        `json:"exclude_authors"`
+	ExcludeCategories []string        `json:"exclude_categories"`
+	ExcludeTitles     []*titlePattern `json:"exclude_titles"`
+	IncludeAuthors    []string        `json:"include_authors"`
+	IncludeCategories []string        `json:"include_categories"`
+	IncludeTitles     []*titlePattern `json:"include_titles"`
+}
+
+// titlePattern is a regular expression matched against entry titles.
+type titlePattern struct {
+	*regexp.Regexp
+}
+
+// UnmarshalText compiles a pattern from configuration.
+func (p *titlePattern) UnmarshalText(text []byte) error {
+	re, err := regexp.Compile(string(text))
+	if err != nil {
+		return xerrors.Errorf("error compiling title pattern: %w", err)
+	}
+
+	p.Regexp = re
+	return nil
+}
+
+// feedFilters are entry filters keyed by feed URL.
+type feedFilters map[string]*entryFilter
+
+// UnmarshalText parses filters from configuration, which are a JSON object
+// keyed by feed URL like:
+//
+//	{"https://example.com/feed.atom": {"include_categories": ["spring"]}}
+func (f *feedFilters) UnmarshalText(text []byte) error {
+	filters := make(map[string]*entryFilter)
+	if err := json.Unmarshal(text, &filters); err != nil {
+		return xerrors.Errorf("error parsing feed filters: %w", err)
+	}
+
+	*f = filters
+	return nil
+}
+
+// Match is whether the entry passes the filter.
+func (f *entryFilter) Match(entry *Entry) bool {
+	if len(f.IncludeAuthors) > 0 && !containsFold(f.IncludeAuthors, entry.AuthorName) {
+		return false
+	}
+
+	if containsFold(f.ExcludeAuthors, entry.AuthorName) {
+		return false
+	}
+
+	if len(f.IncludeCategories) > 0 && !hasCategory(entry, f.IncludeCategories) {
+		return false
+	}
+
+	if hasCategory(entry, f.ExcludeCategories) {
+		return false
+	}
+
+	if len(f.IncludeTitles) > 0 && !matchesAnyTitle(f.IncludeTitles, entry.Title) {
+		return false
+	}
+
+	return !matchesAnyTitle(f.ExcludeTitles, entry.Title)
+}
+
+// Returns the entries that pass the filter, which may be nil for no
+// filtering.
+func filterEntries(entries []*Entry, filter *entryFilter) []*Entry {
+	if filter == nil {
+		return entries
+	}
+
+	var filtered []*Entry
+	for _, entry := range entries {
+		if filter.Match(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
+func containsFold(vals []string, s string) bool {
+	for _, val := range vals {
+		if strings.EqualFold(val, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasCategory(entry *Entry, terms []string) bool {
+	for _, category := range entry.Categories {
+		if containsFold(terms, category.Term) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAnyTitle(patterns []*titlePattern, title string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(title) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryFilterMatch(t *testing.T) {
+	entry := &Entry{
+		Title:      "Mount Rainier",
+		AuthorName: "Brandur",
+		Categories: []*Category{{Term: "photos"}, {Term: "Spring"}},
+	}
+
+	mustParseFilter := func(t *testing.T, s string) *entryFilter {
+		t.Helper()
+
+		var filters feedFilters
+		require.NoError(t, filters.UnmarshalText([]byte(`{"feed": `+s+`}`)))
+		return filters["feed"]
+	}
+
+	for _, tt := range []struct {
+		name   string
+		filter string
+		match  bool
+	}{
+		{"Empty", `{}`, true},
+		{"IncludeCategory", `{"include_categories": ["spring"]}`, true},
+		{"IncludeCategoryMissing", `{"include_categories": ["drafts"]}`, false},
+		{"ExcludeCategory", `{"exclude_categories": ["photos"]}`, false},
+		{"ExcludeCategoryMissing", `{"exclude_categories": ["drafts"]}`, true},
+		{"IncludeAuthor", `{"include_authors": ["brandur"]}`, true},
+		{"IncludeAuthorMissing", `{"include_authors": ["someone"]}`, false},
+		{"ExcludeAuthor", `{"exclude_authors": ["Brandur"]}`, false},
+		{"IncludeTitle", `{"include_titles": ["^Mount "]}`, true},
+		{"IncludeTitleMissing", `{"include_titles": ["^Re: "]}`, false},
+		{"ExcludeTitle", `{"exclude_titles": ["(?i)rainier"]}`, false},
+		{"ExcludeTitleMissing", `{"exclude_titles": ["^Draft"]}`, true},
+		{"IncludeAndExclude", `{"include_categories": ["spring"], "exclude_titles": ["Rainier"]}`, false},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.match, mustParseFilter(t, tt.filter).Match(entry))
+		})
+	}
+}
+
+func TestFilterEntries(t *testing.T) {
+	entries := []*Entry{
+		{Title: "Mount Rainier"},
+		{Title: "Draft: Golden Gardens"},
+	}
+
+	require.Equal(t, entries, filterEntries(entries, nil))
+
+	require.Equal(t, entries[:1], filterEntries(entries, &entryFilter{
+		ExcludeTitles: []*titlePattern{mustTitlePattern(t, "^Draft")},
+	}))
+	require.Empty(t, filterEntries(entries, &entryFilter{
+		IncludeCategories: []string{"spring"},
+	}))
+}
+
+func TestFeedFiltersUnmarshalText(t *testing.T) {
+	var filters feedFilters
+	require.ErrorContains(t, filters.UnmarshalText([]byte(`[]`)), "error parsing feed filters")
+	require.ErrorContains(t, filters.UnmarshalText([]byte(`{"feed": {"include_titles": ["("]}}`)),
+		"error compiling title pattern")
+}
+
+func TestFeedFiltersEnv(t *testing.T) {
+	type config struct {
+		FeedFilters feedFilters `env:"FEED_FILTERS"`
+	}
+
+	{
+		var c config
+		require.NoError(t, env.Parse(&c))
+		require.Nil(t, c.FeedFilters)
+	}
+
+	{
+		t.Setenv("FEED_FILTERS", `{"https://example.com/feed.atom": {"include_categories": ["spring"]}}`)
+		var c config
+		require.NoError(t, env.Parse(&c))
+		require.Equal(t, []string{"spring"}, c.FeedFilters["https://example.com/feed.atom"].IncludeCategories)
+	}
+}
+
+func mustTitlePattern(t *testing.T, s string) *titlePattern {
+	t.Helper()
+
+	pattern := &titlePattern{}
+	require.NoError(t, pattern.UnmarshalText([]byte(s)))
+	return pattern
+}
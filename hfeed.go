@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/xerrors"
+)
+
+// Layouts for a `dt-*` microformats property. Microformats2 is permissive
+// about date formats, so in addition to RFC 3339 allow a space instead of a
+// `T`, a zone without a colon, and dates without a time.
+var hFeedDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+// Parses a plain HTML page marked up with microformats2 into an Atom Feed so
+// that it can go through the same pipeline as a syndication feed. Entries are
+// the `h-entry` elements in the page's `h-feed`, or if it has none, every
+// top-level `h-entry` in the page.
+//
+// This isn't a complete microformats2 parser. It handles the properties that
+// map onto an entry (`p-name`, `p-summary`, `e-content`, `dt-published`,
+// `dt-updated`, `u-url`, `u-uid`, `p-category`, and `p-author`) and ignores
+// the rest.
+//
+// URL properties are resolved against pageURL, the URL the page was fetched
+// from, so that entry IDs and links are unique across sites.
+func parseHFeed(data []byte, pageURL *url.URL) (*Feed, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, xerrors.Errorf("error parsing HTML: %w", err)
+	}
+
+	feed := &Feed{}
+
+	var entryNodes []*html.Node
+	if hFeed := findMicroformat(doc, "h-feed"); hFeed != nil {
+		feed.Title = microformatText(findProperty(hFeed, "p-name"))
+		entryNodes = findMicroformats(hFeed, "h-entry")
+	} else {
+		entryNodes = findMicroformats(doc, "h-entry")
+	}
+
+	for _, node := range entryNodes {
+		// One bad entry shouldn't take down the whole page.
+		entry, err := hEntryToEntry(node, pageURL)
+		if err != nil {
+			logger.Warnf("Skipping h-entry on %s: %v", pageURL, err)
+			continue
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed, nil
+}
+
+// Converts an `h-entry` element to an Atom entry, resolving URL properties
+// against pageURL.
+func hEntryToEntry(node *html.Node, pageURL *url.URL) (*Entry, error) {
+	resolve := func(val string) string {
+		if pageURL == nil {
+			return val
+		}
+		return resolveURL(pageURL, val)
+	}
+
+	entry := &Entry{
+		Title:   microformatText(findProperty(node, "p-name")),
+		Summary: microformatText(findProperty(node, "p-summary")),
+	}
+
+	if content := findProperty(node, "e-content"); content != nil {
+		innerHTML, err := renderInnerHTML(content)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Content = &EntryContent{Content: innerHTML, Type: "html"}
+	}
+
+	if urlNode := findProperty(node, "u-url"); urlNode != nil {
+		entry.Link = &Link{Rel: "alternate", Href: resolve(microformatURL(urlNode))}
+	}
+
+	switch {
+	case findProperty(node, "u-uid") != nil:
+		entry.ID = resolve(microformatURL(findProperty(node, "u-uid")))
+	case entry.Link != nil:
+		entry.ID = entry.Link.Href
+	}
+
+	if published := findProperty(node, "dt-published"); published != nil {
+		t, err := parseHFeedDate(microformatDate(published))
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Published = t
+		entry.Updated = t
+	}
+
+	if updated := findProperty(node, "dt-updated"); updated != nil {
+		t, err := parseHFeedDate(microformatDate(updated))
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Updated = t
+	}
+
+	// An author is usually a nested `h-card`, in which case its name and URL
+	// are properties of the card, but may also be plain text.
+	if author := findProperty(node, "p-author"); author != nil {
+		if hasClass(author, "h-card") {
+			entry.AuthorName = microformatText(findProperty(author, "p-name"))
+			switch urlNode := findProperty(author, "u-url"); {
+			case urlNode != nil:
+				entry.AuthorURI = resolve(microformatURL(urlNode))
+
+			// A card on a link with no explicit URL implies the link's.
+			case author.DataAtom == atom.A:
+				href, _ := getAttr(author, "href")
+				entry.AuthorURI = resolve(href)
+			}
+		}
+
+		if entry.AuthorName == "" {
+			entry.AuthorName = microformatText(author)
+		}
+	}
+
+	for _, category := range findProperties(node, "p-category") {
+		entry.Categories = append(entry.Categories, &Category{Term: microformatText(category)})
+	}
+
+	return entry, nil
+}
+
+// Finds the first element with the given microformat root class (e.g.
+// `h-feed`) anywhere under node.
+func findMicroformat(node *html.Node, class string) *html.Node {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if hasClass(child, class) {
+			return child
+		}
+
+		if found := findMicroformat(child, class); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// Finds all elements with the given microformat root class under node, but
+// not those nested inside another match (e.g. an `h-entry` quoted inside an
+// `h-entry`).
+func findMicroformats(node *html.Node, class string) []*html.Node {
+	var found []*html.Node
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if hasClass(child, class) {
+			found = append(found, child)
+			continue
+		}
+
+		found = append(found, findMicroformats(child, class)...)
+	}
+
+	return found
+}
+
+// Finds the first element with the given property class that belongs to the
+// microformat rooted at node.
+func findProperty(node *html.Node, class string) *html.Node {
+	properties := findProperties(node, class)
+	if len(properties) < 1 {
+		return nil
+	}
+	return properties[0]
+}
+
+// Finds all elements with the given property class that belong to the
+// microformat rooted at node. Properties are searched for in descendants, but
+// not inside nested microformats, whose properties belong to them instead.
+// A nested microformat may itself be a property though, like `p-author
+// h-card`.
+func findProperties(node *html.Node, class string) []*html.Node {
+	var found []*html.Node
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+
+		if hasClass(child, class) {
+			found = append(found, child)
+		}
+
+		if !isMicroformatRoot(child) {
+			found = append(found, findProperties(child, class)...)
+		}
+	}
+
+	return found
+}
+
+func hasClass(node *html.Node, class string) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+
+	for _, attr := range node.Attr {
+		if attr.Key == "class" {
+			for _, c := range strings.Fields(attr.Val) {
+				if c == class {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Whether the node is the root of a microformat, which is indicated by a
+// class with an `h-` prefix.
+func isMicroformatRoot(node *html.Node) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == "class" {
+			for _, c := range strings.Fields(attr.Val) {
+				if strings.HasPrefix(c, "h-") {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func getAttr(node *html.Node, key string) (string, bool) {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// Gets the value of a `dt-*` property, which comes from a `datetime`
+// attribute on `<time>`, `<ins>`, or `<del>`, a `title` on `<abbr>`, or
+// otherwise the element's text.
+func microformatDate(node *html.Node) string {
+	switch node.DataAtom { //nolint:exhaustive
+	case atom.Time, atom.Ins, atom.Del:
+		if val, ok := getAttr(node, "datetime"); ok {
+			return val
+		}
+	case atom.Abbr:
+		if val, ok := getAttr(node, "title"); ok {
+			return val
+		}
+	}
+
+	return microformatText(node)
+}
+
+// Gets the value of a `p-*` property, which is its text content with
+// whitespace collapsed. Images contribute their alt text.
+func microformatText(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	if node.DataAtom == atom.Img {
+		val, _ := getAttr(node, "alt")
+		return strings.TrimSpace(val)
+	}
+
+	var buf strings.Builder
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			buf.WriteString(n.Data)
+		case n.DataAtom == atom.Img:
+			if val, ok := getAttr(n, "alt"); ok {
+				buf.WriteString(val)
+			}
+		case n.DataAtom == atom.Script, n.DataAtom == atom.Style:
+			return
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// Gets the value of a `u-*` property, which comes from the URL attribute
+// appropriate for the element, or otherwise its text.
+func microformatURL(node *html.Node) string {
+	var key string
+
+	switch node.DataAtom { //nolint:exhaustive
+	case atom.A, atom.Area, atom.Link:
+		key = "href"
+	case atom.Audio, atom.Iframe, atom.Img, atom.Source, atom.Video:
+		key = "src"
+	case atom.Data:
+		key = "value"
+	case atom.Object:
+		key = "data"
+	}
+
+	if key != "" {
+		if val, ok := getAttr(node, key); ok {
+			return strings.TrimSpace(val)
+		}
+	}
+
+	return microformatText(node)
+}
+
+func parseHFeedDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range hFeedDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, xerrors.Errorf("error parsing microformats date %q", s)
+}
+
+// Renders the children of a node, but not the node itself.
+func renderInnerHTML(node *html.Node) (string, error) {
+	var buf bytes.Buffer
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&buf, child); err != nil {
+			return "", xerrors.Errorf("error rendering HTML: %w", err)
+		}
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
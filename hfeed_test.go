@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const sampleHFeed = `<!DOCTYPE html>
+<html lang=en>
+<head><title>Sequences</title><meta charset=utf-8></head>
+<body>
+	<div class="h-card"><span class="p-name">Site Card</span></div>
+	<main class="h-feed">
+		<h1 class="p-name">Sequences</h1>
+		<article class="h-entry">
+			<h2><a class="p-name u-url" href="/sequences/030">Mount Rainier</a></h2>
+			<time class="dt-published" datetime="2022-11-09T02:11:12-08:00">November 9</time>
+			<time class="dt-updated" datetime="2022-11-10 10:11:12Z">November 10</time>
+			<a class="p-author h-card" href="https://example.com"><span class="p-name">Brandur</span></a>
+			<p class="p-summary">A short summary.</p>
+			<div class="e-content">
+				<p>Full <a href="/nanoglyphs">content</a>.</p>
+				<blockquote class="h-entry"><span class="p-name">Quoted</span></blockquote>
+			</div>
+			<a class="p-category" href="/travel">travel</a>
+			<span class="p-category">spring</span>
+		</article>
+		<article class="h-entry">
+			<a class="u-url" href="/sequences/029"><span class="p-name">Second</span></a>
+			<data class="u-uid" value="tag:example.com,2022:sequences/029">tag:example.com,2022:sequences/029</data>
+			<time class="dt-published" datetime="2022-11-08">Nov 8</time>
+			<span class="p-author">Someone Else</span>
+		</article>
+	</main>
+</body>
+</html>`
+
+func TestParseHFeed(t *testing.T) {
+	feed, err := parseHFeed([]byte(sampleHFeed), mustParseURL("https://example.com/sequences"))
+	require.NoError(t, err)
+	require.Equal(t, "Sequences", feed.Title)
+	require.Len(t, feed.Entries, 2)
+
+	{
+		entry := feed.Entries[0]
+		require.Equal(t, "Mount Rainier", entry.Title)
+		require.Equal(t, "https://example.com/sequences/030", entry.Link.Href)
+		require.Equal(t, "https://example.com/sequences/030", entry.ID)
+		require.Equal(t, "A short summary.", entry.Summary)
+		require.Equal(t, `<p>Full <a href="/nanoglyphs">content</a>.</p>
+				<blockquote class="h-entry"><span class="p-name">Quoted</span></blockquote>`, entry.Content.Content)
+		require.Equal(t, "Brandur", entry.AuthorName)
+		require.Equal(t, "https://example.com", entry.AuthorURI)
+		require.Equal(t, []*Category{{Term: "travel"}, {Term: "spring"}}, entry.Categories)
+		require.Equal(t, time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC), entry.Published)
+		require.Equal(t, time.Date(2022, 11, 10, 10, 11, 12, 0, time.UTC), entry.Updated)
+	}
+
+	{
+		entry := feed.Entries[1]
+		require.Equal(t, "Second", entry.Title)
+		require.Equal(t, "tag:example.com,2022:sequences/029", entry.ID)
+		require.Equal(t, "https://example.com/sequences/029", entry.Link.Href)
+		require.Equal(t, "Someone Else", entry.AuthorName)
+		require.Nil(t, entry.Content)
+		require.Equal(t, time.Date(2022, 11, 8, 0, 0, 0, 0, time.UTC), entry.Published)
+	}
+}
+
+func TestParseHFeedNoFeed(t *testing.T) {
+	// Without an h-feed, top-level h-entries anywhere in the page are used.
+	feed, err := parseHFeed([]byte(`<html><body>
+		<div><article class="h-entry"><h1 class="p-name">Standalone</h1></article></div>
+	</body></html>`), nil)
+	require.NoError(t, err)
+	require.Len(t, feed.Entries, 1)
+	require.Equal(t, "Standalone", feed.Entries[0].Title)
+}
+
+func TestParseHFeedBadDate(t *testing.T) {
+	// An entry with an unparseable date is skipped rather than failing the
+	// whole page.
+	feed, err := parseHFeed([]byte(`<html><body><main class="h-feed">
+		<article class="h-entry"><h1 class="p-name">Bad</h1><time class="dt-published" datetime="last week"></time></article>
+		<article class="h-entry"><h1 class="p-name">Good</h1><time class="dt-published" datetime="2022-11-08"></time></article>
+	</main></body></html>`), mustParseURL("https://example.com/"))
+	require.NoError(t, err)
+	require.Len(t, feed.Entries, 1)
+	require.Equal(t, "Good", feed.Entries[0].Title)
+}
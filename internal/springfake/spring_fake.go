@@ -0,0 +1,207 @@
+// Package springfake provides an in-memory fake of a Spring '83 server for use
+// in tests and local development. It implements the parts of the
+// specification that a publishing client interacts with:
+//
+//   - Keys must be well-formed and currently valid, and the spec's test key is
+//     always rejected.
+//   - Boards must be signed by their key in a `Spring-Signature` header.
+//   - Boards must be no larger than 2217 bytes.
+//   - Boards must carry a `<time datetime="...">` timestamp that's not in the
+//     future, and newer than any board already stored for the key.
+//
+// It's an http.Handler, so it works with httptest.NewServer.
+//
+// See: https://github.com/robinsloan/spring-83/blob/main/draft-20220629.md
+package springfake
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// MaxBoardSize is the maximum size of a board in bytes.
+const MaxBoardSize = 2217
+
+// TestPublicKey is the test key defined by the Spring '83 specification, which
+// content is never accepted for.
+const TestPublicKey = "ab589f4dde9fce4180fcf42c7b05185b0a02a5d682e353fa39177995083e0583"
+
+// The maximum valid lifetime of a key as dictated by the specification.
+const maxKeyLifetime = 2 * 365 * 24 * time.Hour
+
+// From spec: <time datetime="YYYY-MM-DDTHH:MM:SSZ">.
+const timestampFormat = "2006-01-02T15:04:05Z"
+
+var (
+	keyRE       = regexp.MustCompile(`\A[0-9a-f]{57}83e(0[1-9]|1[0-2])(\d\d)\z`)
+	timestampRE = regexp.MustCompile(`<time datetime="(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ)">`)
+)
+
+// Board is a board stored by the server.
+type Board struct {
+	Content   []byte
+	Signature string
+	Timestamp time.Time
+}
+
+// Server is a fake Spring '83 server. It's safe for concurrent use.
+type Server struct {
+	// Now returns the current time, which is used to check key validity and
+	// board timestamps. Defaults to time.Now.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	boards map[string]*Board
+}
+
+// NewServer initializes a new server with no boards.
+func NewServer() *Server {
+	return &Server{
+		Now:    time.Now,
+		boards: map[string]*Board{},
+	}
+}
+
+// Board gets the board stored for the given key, or nil if there isn't one.
+func (s *Server) Board(publicKey string) *Board {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.boards[publicKey]
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Spring-Version", "83")
+
+	publicKey := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/"))
+
+	switch r.Method {
+	case http.MethodGet:
+		s.handleGet(w, publicKey)
+	case http.MethodPut:
+		s.handlePut(w, r, publicKey)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, publicKey string) {
+	board := s.Board(publicKey)
+	if board == nil {
+		writeError(w, http.StatusNotFound, "board not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	w.Header().Set("Spring-Signature", board.Signature)
+	_, _ = w.Write(board.Content)
+}
+
+func (s *Server) handlePut(w http.ResponseWriter, r *http.Request, publicKey string) {
+	now := s.Now()
+
+	if publicKey == TestPublicKey {
+		writeError(w, http.StatusUnauthorized, "content for the test key is never accepted")
+		return
+	}
+
+	publicKeyBytes, status, err := checkKey(publicKey, now)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+
+	// Read one extra byte to tell a board of exactly the maximum size apart
+	// from one that's larger.
+	content, err := io.ReadAll(io.LimitReader(r.Body, MaxBoardSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "error reading body")
+		return
+	}
+
+	if len(content) > MaxBoardSize {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("board larger than maximum of %d bytes", MaxBoardSize))
+		return
+	}
+
+	signatureHex := r.Header.Get("Spring-Signature")
+	signature, err := hex.DecodeString(signatureHex)
+	if err != nil || !ed25519.Verify(publicKeyBytes, content, signature) {
+		writeError(w, http.StatusUnauthorized, "missing or invalid Spring-Signature")
+		return
+	}
+
+	matches := timestampRE.FindSubmatch(content)
+	if matches == nil {
+		writeError(w, http.StatusBadRequest, "board is missing a <time> timestamp")
+		return
+	}
+
+	timestamp, err := time.Parse(timestampFormat, string(matches[1]))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "board has an invalid <time> timestamp")
+		return
+	}
+
+	if timestamp.After(now) {
+		writeError(w, http.StatusBadRequest, "board timestamp is in the future")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing := s.boards[publicKey]; existing != nil && !timestamp.After(existing.Timestamp) {
+		writeError(w, http.StatusConflict, "a board with the same or a newer timestamp already exists")
+		return
+	}
+
+	s.boards[publicKey] = &Board{
+		Content:   content,
+		Signature: signatureHex,
+		Timestamp: timestamp,
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Checks that a key is well-formed and valid at the given time, returning its
+// raw bytes, or otherwise a status code and error to respond with.
+func checkKey(publicKey string, now time.Time) (ed25519.PublicKey, int, error) {
+	matches := keyRE.FindStringSubmatch(publicKey)
+	if matches == nil {
+		return nil, http.StatusForbidden, xerrors.Errorf("key %q is not a valid Spring '83 key", publicKey)
+	}
+
+	month, _ := strconv.Atoi(matches[1])
+	year, _ := strconv.Atoi(matches[2])
+
+	expiryMonth := time.Date(now.Year()/100*100+year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	if !now.Before(expiryMonth.AddDate(0, 1, 0)) {
+		return nil, http.StatusForbidden, xerrors.Errorf("key %s is expired", publicKey)
+	}
+
+	if expiryMonth.Add(-maxKeyLifetime).After(now) {
+		return nil, http.StatusForbidden, xerrors.Errorf("key %s is not yet valid", publicKey)
+	}
+
+	publicKeyBytes, _ := hex.DecodeString(publicKey) // format checked by regex above
+	return publicKeyBytes, 0, nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/plain;charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintln(w, message)
+}
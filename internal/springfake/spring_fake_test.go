@@ -0,0 +1,168 @@
+package springfake
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A valid key that expires at the end of November 2024.
+const (
+	samplePrivateKey = "90ba51828ecc30132d4707d55d24456fbd726514cf56ab4668b62392798e2540"
+	samplePublicKey  = "e90e9091b13a6e5194c1fed2728d1fdb6de7df362497d877b8c0b8f0883e1124"
+)
+
+func TestServer(t *testing.T) {
+	seed, err := hex.DecodeString(samplePrivateKey)
+	require.NoError(t, err)
+	privateKey := ed25519.NewKeyFromSeed(seed)
+
+	now := time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC)
+
+	board := func(timestamp time.Time) string {
+		return `<time datetime="` + timestamp.Format(timestampFormat) + `"><p>hello</p>`
+	}
+
+	sign := func(content string) string {
+		return hex.EncodeToString(ed25519.Sign(privateKey, []byte(content)))
+	}
+
+	setup := func(t *testing.T) (*Server, *httptest.Server) {
+		t.Helper()
+
+		fake := NewServer()
+		fake.Now = func() time.Time { return now }
+
+		server := httptest.NewServer(fake)
+		t.Cleanup(server.Close)
+
+		return fake, server
+	}
+
+	put := func(t *testing.T, url, publicKey, content, signature string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPut, url+"/"+publicKey, strings.NewReader(content))
+		require.NoError(t, err)
+		req.Header.Set("Spring-Signature", signature)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	t.Run("PutAndGet", func(t *testing.T) {
+		fake, server := setup(t)
+
+		content := board(now.Add(-time.Minute))
+		require.Equal(t, http.StatusNoContent, put(t, server.URL, samplePublicKey, content, sign(content)))
+		require.Equal(t, content, string(fake.Board(samplePublicKey).Content))
+
+		resp, err := http.Get(server.URL + "/" + samplePublicKey)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, sign(content), resp.Header.Get("Spring-Signature"))
+		require.Equal(t, "83", resp.Header.Get("Spring-Version"))
+	})
+
+	t.Run("GetNotFound", func(t *testing.T) {
+		_, server := setup(t)
+
+		resp, err := http.Get(server.URL + "/" + samplePublicKey)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("OlderBoardConflict", func(t *testing.T) {
+		_, server := setup(t)
+
+		newer := board(now.Add(-time.Minute))
+		require.Equal(t, http.StatusNoContent, put(t, server.URL, samplePublicKey, newer, sign(newer)))
+
+		older := board(now.Add(-time.Hour))
+		require.Equal(t, http.StatusConflict, put(t, server.URL, samplePublicKey, older, sign(older)))
+
+		// Same timestamp is a conflict too.
+		require.Equal(t, http.StatusConflict, put(t, server.URL, samplePublicKey, newer, sign(newer)))
+	})
+
+	t.Run("InvalidSignature", func(t *testing.T) {
+		_, server := setup(t)
+
+		content := board(now.Add(-time.Minute))
+		require.Equal(t, http.StatusUnauthorized, put(t, server.URL, samplePublicKey, content, sign("other content")))
+		require.Equal(t, http.StatusUnauthorized, put(t, server.URL, samplePublicKey, content, "not-hex"))
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		_, server := setup(t)
+
+		content := board(now.Add(-time.Minute)) + strings.Repeat("a", MaxBoardSize)
+		require.Equal(t, http.StatusRequestEntityTooLarge, put(t, server.URL, samplePublicKey, content, sign(content)))
+	})
+
+	t.Run("MissingTimestamp", func(t *testing.T) {
+		_, server := setup(t)
+
+		content := "<p>hello</p>"
+		require.Equal(t, http.StatusBadRequest, put(t, server.URL, samplePublicKey, content, sign(content)))
+	})
+
+	t.Run("FutureTimestamp", func(t *testing.T) {
+		_, server := setup(t)
+
+		content := board(now.Add(time.Hour))
+		require.Equal(t, http.StatusBadRequest, put(t, server.URL, samplePublicKey, content, sign(content)))
+	})
+
+	t.Run("TestKey", func(t *testing.T) {
+		_, server := setup(t)
+
+		content := board(now.Add(-time.Minute))
+		require.Equal(t, http.StatusUnauthorized, put(t, server.URL, TestPublicKey, content, sign(content)))
+	})
+
+	t.Run("ExpiredKey", func(t *testing.T) {
+		fake, server := setup(t)
+		fake.Now = func() time.Time { return time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC) }
+
+		content := board(now)
+		require.Equal(t, http.StatusForbidden, put(t, server.URL, samplePublicKey, content, sign(content)))
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		_, server := setup(t)
+
+		content := board(now.Add(-time.Minute))
+		require.Equal(t, http.StatusForbidden, put(t, server.URL, "not-a-key", content, sign(content)))
+	})
+}
+
+func TestCheckKey(t *testing.T) {
+	yearMonthDate := func(year, month int) time.Time {
+		return time.Date(year, time.Month(month), 9, 10, 11, 12, 0, time.UTC)
+	}
+
+	_, _, err := checkKey(samplePublicKey, yearMonthDate(2022, 11))
+	require.NoError(t, err)
+
+	_, _, err = checkKey(samplePublicKey, time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC))
+	require.NoError(t, err)
+
+	_, status, err := checkKey(samplePublicKey, yearMonthDate(2024, 12))
+	require.ErrorContains(t, err, "expired")
+	require.Equal(t, http.StatusForbidden, status)
+
+	_, _, err = checkKey(samplePublicKey, yearMonthDate(2022, 10))
+	require.ErrorContains(t, err, "not yet valid")
+}
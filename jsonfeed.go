@@ -0,0 +1,160 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Prefix of the `version` field of a JSON Feed, which is a URL of the form
+// `https://jsonfeed.org/version/1.1`.
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+// JSONFeed represents a JSON Feed (version 1 or 1.1). Only the subset of it
+// that's needed to produce entries is modeled.
+//
+// See: https://www.jsonfeed.org/version/1.1/
+type JSONFeed struct {
+	Version     string            `json:"version"`
+	Title       string            `json:"title"`
+	HomePageURL string            `json:"home_page_url"`
+	FeedURL     string            `json:"feed_url"`
+	Authors     []*JSONFeedAuthor `json:"authors"`
+	Items       []*JSONFeedItem   `json:"items"`
+
+	// Deprecated in 1.1 in favor of Authors, but still common.
+	Author *JSONFeedAuthor `json:"author"`
+}
+
+// JSONFeedAuthor is an author of a JSON Feed or one of its items.
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// JSONFeedItem is a single item in a JSON Feed.
+type JSONFeedItem struct {
+	ID            string            `json:"id"`
+	URL           string            `json:"url"`
+	Title         string            `json:"title"`
+	ContentHTML   string            `json:"content_html"`
+	ContentText   string            `json:"content_text"`
+	Summary       string            `json:"summary"`
+	DatePublished string            `json:"date_published"`
+	DateModified  string            `json:"date_modified"`
+	Authors       []*JSONFeedAuthor `json:"authors"`
+	Tags          []string          `json:"tags"`
+
+	// Deprecated in 1.1 in favor of Authors, but still common.
+	Author *JSONFeedAuthor `json:"author"`
+}
+
+// ToFeed converts a JSON Feed into the Atom-based Feed used everywhere else
+// so that it can go through the same pipeline.
+func (f *JSONFeed) ToFeed() (*Feed, error) {
+	if !strings.HasPrefix(f.Version, jsonFeedVersionPrefix) {
+		return nil, xerrors.Errorf("unknown JSON Feed version: %q", f.Version)
+	}
+
+	feed := &Feed{Title: f.Title}
+
+	if f.HomePageURL != "" {
+		feed.Links = append(feed.Links, &Link{Rel: "alternate", Href: f.HomePageURL})
+	}
+	if f.FeedURL != "" {
+		feed.Links = append(feed.Links, &Link{Rel: "self", Href: f.FeedURL})
+	}
+
+	feedAuthor := firstJSONFeedAuthor(f.Authors, f.Author)
+
+	for _, item := range f.Items {
+		// One bad item shouldn't take down the whole feed.
+		entry, err := item.ToEntry()
+		if err != nil {
+			logger.Warnf("Skipping JSON Feed item %q: %v", item.Title, err)
+			continue
+		}
+
+		// Items inherit the feed's author if they don't specify their own.
+		if entry.AuthorName == "" && entry.AuthorURI == "" && feedAuthor != nil {
+			entry.AuthorName = feedAuthor.Name
+			entry.AuthorURI = feedAuthor.URL
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed, nil
+}
+
+// ToEntry converts a JSON Feed item to an Atom entry. Content comes from
+// `content_html` if present, and otherwise from `content_text`, which is
+// escaped and wrapped in a paragraph.
+func (i *JSONFeedItem) ToEntry() (*Entry, error) {
+	entry := &Entry{
+		Title:   i.Title,
+		Summary: i.Summary,
+		ID:      i.ID,
+	}
+
+	switch {
+	case i.ContentHTML != "":
+		entry.Content = &EntryContent{Content: i.ContentHTML, Type: "html"}
+	case i.ContentText != "":
+		entry.Content = &EntryContent{Content: "<p>" + html.EscapeString(i.ContentText) + "</p>", Type: "html"}
+		entry.ContentEscaped = true
+	}
+
+	if i.URL != "" {
+		entry.Link = &Link{Rel: "alternate", Href: i.URL}
+	}
+
+	if i.DatePublished != "" {
+		published, err := parseJSONFeedDate(i.DatePublished)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Published = published
+		entry.Updated = published
+	}
+	if i.DateModified != "" {
+		modified, err := parseJSONFeedDate(i.DateModified)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Updated = modified
+	}
+
+	if author := firstJSONFeedAuthor(i.Authors, i.Author); author != nil {
+		entry.AuthorName = author.Name
+		entry.AuthorURI = author.URL
+	}
+
+	for _, tag := range i.Tags {
+		entry.Categories = append(entry.Categories, &Category{Term: tag})
+	}
+
+	return entry, nil
+}
+
+// Gets the first of a list of 1.1 authors, falling back to a deprecated 1.0
+// author if there were none.
+func firstJSONFeedAuthor(authors []*JSONFeedAuthor, author *JSONFeedAuthor) *JSONFeedAuthor {
+	if len(authors) > 0 {
+		return authors[0]
+	}
+	return author
+}
+
+// Parses a JSON Feed date, which the specification requires to be RFC 3339.
+func parseJSONFeedDate(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, xerrors.Errorf("error parsing JSON Feed date %q", s)
+	}
+	return t.UTC(), nil
+}
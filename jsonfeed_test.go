@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const sampleJSONFeed = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Sequences",
+	"home_page_url": "https://example.com/sequences",
+	"feed_url": "https://example.com/sequences.json",
+	"authors": [{"name": "Brandur", "url": "https://example.com"}],
+	"items": [
+		{
+			"id": "tag:example.com,2022:sequences/030",
+			"url": "https://example.com/sequences/030",
+			"title": "Mount Rainier",
+			"content_html": "<p>Full content.</p>",
+			"summary": "A short summary.",
+			"date_published": "2022-11-09T02:11:12-08:00",
+			"date_modified": "2022-11-10T10:11:12Z",
+			"tags": ["travel", "spring"]
+		},
+		{
+			"id": "tag:example.com,2022:sequences/029",
+			"title": "Text only",
+			"content_text": "Fish & chips.",
+			"date_published": "2022-11-08T10:11:12Z",
+			"authors": [{"name": "Someone Else"}]
+		}
+	]
+}`
+
+func TestJSONFeedToFeed(t *testing.T) {
+	var jsonFeed JSONFeed
+	require.NoError(t, json.Unmarshal([]byte(sampleJSONFeed), &jsonFeed))
+
+	feed, err := jsonFeed.ToFeed()
+	require.NoError(t, err)
+	require.Equal(t, "Sequences", feed.Title)
+	require.Equal(t, []*Link{
+		{Rel: "alternate", Href: "https://example.com/sequences"},
+		{Rel: "self", Href: "https://example.com/sequences.json"},
+	}, feed.Links)
+	require.Len(t, feed.Entries, 2)
+
+	{
+		entry := feed.Entries[0]
+		require.Equal(t, "Mount Rainier", entry.Title)
+		require.Equal(t, "tag:example.com,2022:sequences/030", entry.ID)
+		require.Equal(t, "https://example.com/sequences/030", entry.Link.Href)
+		require.Equal(t, "<p>Full content.</p>", entry.Content.Content)
+		require.Equal(t, "A short summary.", entry.Summary)
+		require.Equal(t, "Brandur", entry.AuthorName)
+		require.Equal(t, "https://example.com", entry.AuthorURI)
+		require.Equal(t, []*Category{{Term: "travel"}, {Term: "spring"}}, entry.Categories)
+		require.Equal(t, time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC), entry.Published)
+		require.Equal(t, time.Date(2022, 11, 10, 10, 11, 12, 0, time.UTC), entry.Updated)
+	}
+
+	{
+		entry := feed.Entries[1]
+		require.Equal(t, "<p>Fish &amp; chips.</p>", entry.Content.Content)
+		require.Equal(t, "Someone Else", entry.AuthorName)
+		require.Nil(t, entry.Link)
+		require.Equal(t, entry.Published, entry.Updated)
+	}
+}
+
+func TestJSONFeedToFeedBadDate(t *testing.T) {
+	// An item with an unparseable date is skipped rather than failing the
+	// whole feed.
+	var jsonFeed JSONFeed
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"version": "https://jsonfeed.org/version/1.1",
+		"items": [
+			{"id": "1", "title": "Bad", "date_published": "2022-11-09"},
+			{"id": "2", "title": "Also bad", "date_modified": "last week"},
+			{"id": "3", "title": "Good", "date_published": "2022-11-08T10:11:12Z"}
+		]
+	}`), &jsonFeed))
+
+	feed, err := jsonFeed.ToFeed()
+	require.NoError(t, err)
+	require.Len(t, feed.Entries, 1)
+	require.Equal(t, "Good", feed.Entries[0].Title)
+}
+
+func TestJSONFeedToEntryBadDate(t *testing.T) {
+	_, err := (&JSONFeedItem{Title: "Bad Date", DatePublished: "2022-11-09"}).ToEntry()
+	require.EqualError(t, err, `error parsing JSON Feed date "2022-11-09"`)
+}
+
+func TestJSONFeedToFeedUnknownVersion(t *testing.T) {
+	jsonFeed := JSONFeed{Version: "https://example.com/version/1"}
+	_, err := jsonFeed.ToFeed()
+	require.EqualError(t, err, `unknown JSON Feed version: "https://example.com/version/1"`)
+}
+
+func TestJSONFeedContentTextRendered(t *testing.T) {
+	// Text content has to stay text once rendered into a board rather than
+	// becoming markup.
+	item := &JSONFeedItem{Title: "a title", ContentText: "use <script>alert(1)</script> & stuff"}
+
+	entry, err := item.ToEntry()
+	require.NoError(t, err)
+
+	rendered, fallback, err := renderBoard(entry)
+	require.NoError(t, err)
+	require.Equal(t, "none", fallback)
+	require.Contains(t, rendered, "<p>use &lt;script&gt;alert(1)&lt;/script&gt; &amp; stuff</p>")
+	require.NotContains(t, rendered, "<script>")
+}
@@ -28,6 +28,7 @@ var (
 	ErrKeyExpired     = xerrors.New("key is expired")
 	ErrKeyInvalid     = xerrors.New("key is invalid")
 	ErrKeyNotYetValid = xerrors.New("key is not yet valid")
+	ErrKeyTest        = xerrors.New("key is the Spring '83 test key")
 )
 
 // See: https://github.com/robinsloan/spring-83/blob/main/draft-20220629.md#key-format
@@ -38,6 +39,10 @@ var keyRE = regexp.MustCompile(`\A[0-9a-f]{57}83e(0[1-9]|1[0-2])(\d\d)\z`)
 type Key struct {
 	PublicKey      string
 	publicKeyBytes ed25519.PublicKey
+
+	// ExpiresAt is the last second at which the key is valid. It's only set
+	// for keys produced by ParseKey, and is the zero time otherwise.
+	ExpiresAt time.Time
 }
 
 // KeyFromRaw produces a Key from the given raw public key. This is unchecked,
@@ -80,7 +85,13 @@ func ParseKey(key string, now time.Time) (*Key, error) {
 		return nil, ErrKeyNotYetValid
 	}
 
-	return parseKeyUnchecked(key)
+	keyObj, err := parseKeyUnchecked(key)
+	if err != nil {
+		return nil, err
+	}
+
+	keyObj.ExpiresAt = expiresAt
+	return keyObj, nil
 }
 
 func parseKeyUnchecked(publicKey string) (*Key, error) {
@@ -93,7 +104,13 @@ func parseKeyUnchecked(publicKey string) (*Key, error) {
 		return nil, xerrors.Errorf("public key's length is %d, but should be %d", len(publicKeyBytes), ed25519.PublicKeySize)
 	}
 
-	return &Key{publicKey, publicKeyBytes}, nil
+	return &Key{PublicKey: publicKey, publicKeyBytes: publicKeyBytes}, nil
+}
+
+// IsTestKey returns true if this is the test key defined by the Spring '83
+// specification, which servers never accept content for.
+func (kp *Key) IsTestKey() bool {
+	return kp.PublicKey == TestPublicKey
 }
 
 func (kp *Key) Verify(message, sig []byte) bool {
@@ -57,6 +57,11 @@ func TestParseKeyPair(t *testing.T) {
 	})
 }
 
+func TestKeyIsTestKey(t *testing.T) {
+	require.True(t, MustParseKeyPairUnchecked(TestPrivateKey).IsTestKey())
+	require.False(t, MustParseKeyPairUnchecked(samplePrivateKey).IsTestKey())
+}
+
 func TestKeyPairFromRaw(t *testing.T) {
 	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
 	require.NoError(t, err)
@@ -99,6 +104,7 @@ func TestParseKey(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, key, keyObj.PublicKey)
 		require.Equal(t, key, hex.EncodeToString(keyObj.publicKeyBytes))
+		require.Equal(t, time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC), keyObj.ExpiresAt)
 	})
 
 	t.Run("BadFormat", func(t *testing.T) {
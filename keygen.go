@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Format of the `-expires` flag for the `keygen` subcommand.
+const keygenExpiryFormat = "2006-01"
+
+// How often the `keygen` subcommand reports progress.
+const keygenProgressInterval = 10 * time.Second
+
+// Runs the `keygen` subcommand, which brute forces an Ed25519 keypair whose
+// public key ends in the `83eMMYY` suffix required by Spring '83, where MMYY
+// is the key's expiry month. The search runs on all CPUs by default, and
+// produces output in the `.envrc` format.
+//
+// Each attempt has a 1 in 16^7 (~268 million) chance of success, so expect it
+// to take a while.
+//
+//	neospring-bridge keygen [-expires YYYY-MM] [-workers N]
+func runKeygen(ctx context.Context, args []string, out io.Writer) error {
+	now := time.Now()
+
+	flagSet := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	expiresStr := flagSet.String("expires", maxKeyExpiry(now).Format(keygenExpiryFormat),
+		"expiry month of the key as YYYY-MM (default: the latest allowed)")
+	workers := flagSet.Int("workers", runtime.NumCPU(), "number of keys to try in parallel")
+	if err := flagSet.Parse(args); err != nil {
+		return xerrors.Errorf("error parsing flags: %w", err)
+	}
+
+	expires, err := time.Parse(keygenExpiryFormat, *expiresStr)
+	if err != nil {
+		return xerrors.Errorf("error parsing -expires (should be YYYY-MM): %w", err)
+	}
+
+	if *workers < 1 {
+		return xerrors.Errorf("-workers should be at least 1, but was %d", *workers)
+	}
+
+	suffix := keySuffix(expires)
+
+	// Check the expiry on a stand-in key with the same suffix so that one
+	// that's in the past or too far out is caught before spending hours
+	// searching for a key that'd be rejected anyway.
+	if _, err := ParseKey(strings.Repeat("0", 64-len(suffix))+suffix, now); err != nil {
+		return xerrors.Errorf("key expiring %s wouldn't be valid: %w", expires.Format(keygenExpiryFormat), err)
+	}
+
+	logger.Infof("Searching for key with suffix %q (expires: %s) on %d worker(s)",
+		suffix, expires.Format(keygenExpiryFormat), *workers)
+
+	keyPair, err := searchKey(ctx, suffix, *workers, keygenProgressInterval)
+	if err != nil {
+		return err
+	}
+
+	// Make sure that what we found is really usable before handing it over.
+	if _, err := ParseKey(keyPair.PublicKey, now); err != nil {
+		return xerrors.Errorf("generated key %s isn't valid: %w", keyPair.PublicKey, err)
+	}
+
+	fmt.Fprintf(out, "export SPRING_PRIVATE_KEY=%s\n", keyPair.PrivateKey)
+	fmt.Fprintf(out, "export SPRING_PUBLIC_KEY=%s\n", keyPair.PublicKey)
+
+	return nil
+}
+
+// Gets the suffix that a public key expiring in the given month must end
+// with, like `83e1124` for November 2024.
+func keySuffix(expires time.Time) string {
+	return fmt.Sprintf("83e%02d%02d", int(expires.Month()), expires.Year()%100)
+}
+
+// Gets the latest month that a key generated now can expire in. A key becomes
+// valid MaxLifetime before the start of its expiry month, so this is the
+// latest month whose start is no more than MaxLifetime from now.
+func maxKeyExpiry(now time.Time) time.Time {
+	expires := relativeMonth(now, 0)
+	for {
+		next := relativeMonth(expires, 1)
+		if next.Add(-MaxLifetime).After(now) {
+			return expires
+		}
+		expires = next
+	}
+}
+
+// Searches for a keypair whose hex-encoded public key ends with the given hex
+// suffix, trying keys on the given number of workers in parallel. Progress is
+// logged every progressInterval.
+func searchKey(ctx context.Context, suffix string, workers int, progressInterval time.Duration) (*KeyPair, error) {
+	match, err := newSuffixMatcher(suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		found     ed25519.PrivateKey
+		foundOnce sync.Once
+		tries     uint64
+		wg        sync.WaitGroup
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Buffer reads so that each attempt isn't a separate call into
+			// the system's random source.
+			randReader := bufio.NewReaderSize(rand.Reader, 64*ed25519.SeedSize)
+			seed := make([]byte, ed25519.SeedSize)
+
+			// Tries are counted in batches to keep workers from contending on
+			// the shared counter.
+			const batchSize = 1000
+
+			for {
+				for j := 0; j < batchSize; j++ {
+					// Only fails if the system's random source is broken, in
+					// which case there's no recovering.
+					if _, err := io.ReadFull(randReader, seed); err != nil {
+						panic(err)
+					}
+
+					privateKey := ed25519.NewKeyFromSeed(seed)
+					if match(privateKey.Public().(ed25519.PublicKey)) {
+						foundOnce.Do(func() {
+							found = privateKey
+							cancel()
+						})
+						return
+					}
+				}
+
+				atomic.AddUint64(&tries, batchSize)
+
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	start := time.Now()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	expectedTries := math.Pow(16, float64(len(suffix)))
+
+	for {
+		select {
+		case <-done:
+			if found == nil {
+				return nil, xerrors.Errorf("key search cancelled: %w", ctx.Err())
+			}
+
+			keyPair := KeyPairFromRaw(found)
+			logger.Infof("Found key %s after ~%d tries in %v",
+				keyPair.PublicKey, atomic.LoadUint64(&tries), time.Since(start).Round(time.Second))
+			return keyPair, nil
+
+		case <-ticker.C:
+			n := atomic.LoadUint64(&tries)
+			rate := float64(n) / time.Since(start).Seconds()
+
+			// The search is memoryless, so the expected time to a match is
+			// the same no matter how long it's been going. The chance that a
+			// match would've been found by now is more informative as it
+			// goes on.
+			chance := 1 - math.Pow(1-1/expectedTries, float64(n))
+			expected := time.Duration(expectedTries / rate * float64(time.Second))
+
+			logger.Infof("Tried %d keys (%.0f/s); %.1f%% chance of a match by now; expected time to a match: %v",
+				n, rate, chance*100, expected.Round(time.Second))
+		}
+	}
+}
+
+// Produces a function that checks whether a raw public key ends with the
+// given hex suffix without having to hex-encode every candidate.
+func newSuffixMatcher(suffix string) (func(publicKey ed25519.PublicKey) bool, error) {
+	suffix = strings.ToLower(suffix)
+
+	// An odd number of characters means that the first is the low nibble of
+	// a byte, so pad it to decode, and only compare that nibble.
+	oddNibble := len(suffix)%2 == 1
+	padded := suffix
+	if oddNibble {
+		padded = "0" + suffix
+	}
+
+	suffixBytes, err := hex.DecodeString(padded)
+	if err != nil {
+		return nil, xerrors.Errorf("error decoding suffix %q: %w", suffix, err)
+	}
+
+	if len(suffixBytes) > ed25519.PublicKeySize {
+		return nil, xerrors.Errorf("suffix %q is longer than a public key", suffix)
+	}
+
+	return func(publicKey ed25519.PublicKey) bool {
+		tail := publicKey[len(publicKey)-len(suffixBytes):]
+
+		if oddNibble {
+			return tail[0]&0x0f == suffixBytes[0] && bytes.Equal(tail[1:], suffixBytes[1:])
+		}
+
+		return bytes.Equal(tail, suffixBytes)
+	}, nil
+}
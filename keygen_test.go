@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySuffix(t *testing.T) {
+	require.Equal(t, "83e1124", keySuffix(time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, "83e0130", keySuffix(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestMaxKeyExpiry(t *testing.T) {
+	for _, now := range []time.Time{
+		time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+		time.Date(2023, 1, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+	} {
+		expires := maxKeyExpiry(now)
+
+		// The suffix for the month must produce a valid key right now, but the
+		// following month's must not.
+		key := strings.Repeat("0", 57) + keySuffix(expires)
+		_, err := ParseKey(key, now)
+		require.NoError(t, err, now)
+
+		key = strings.Repeat("0", 57) + keySuffix(relativeMonth(expires, 1))
+		_, err = ParseKey(key, now)
+		require.ErrorIs(t, err, ErrKeyNotYetValid, now)
+	}
+}
+
+func TestNewSuffixMatcher(t *testing.T) {
+	publicKey, err := hex.DecodeString(samplePublicKey) // ends in 83e1124
+	require.NoError(t, err)
+
+	for suffix, expected := range map[string]bool{
+		"4":       true,
+		"24":      true,
+		"83e1124": true,
+		"83E1124": true,
+		"93e1124": false,
+		"83e1125": false,
+		"ffffff":  false,
+	} {
+		match, err := newSuffixMatcher(suffix)
+		require.NoError(t, err)
+		require.Equal(t, expected, match(ed25519.PublicKey(publicKey)), suffix)
+	}
+
+	_, err = newSuffixMatcher("not hex")
+	require.Error(t, err)
+
+	_, err = newSuffixMatcher(strings.Repeat("0", 65))
+	require.Error(t, err)
+}
+
+func TestSearchKey(t *testing.T) {
+	ctx := context.Background()
+
+	// A short suffix so that the search finishes quickly.
+	keyPair, err := searchKey(ctx, "83e", 2, time.Hour)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(keyPair.PublicKey, "83e"))
+	require.Equal(t, keyPair.PublicKey, MustParseKeyPairUnchecked(keyPair.PrivateKey).PublicKey)
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := searchKey(ctx, strings.Repeat("0", 64), 2, time.Hour)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRunKeygenInvalidFlags(t *testing.T) {
+	ctx := context.Background()
+
+	// All of these should fail right away rather than after a search.
+	for _, tt := range []struct {
+		args []string
+		err  error
+		msg  string
+	}{
+		{[]string{"-expires", "2020-01"}, ErrKeyExpired, "key expiring 2020-01 wouldn't be valid"},
+		{[]string{"-expires", maxKeyExpiry(time.Now()).AddDate(0, 2, 0).Format(keygenExpiryFormat)}, ErrKeyNotYetValid, "wouldn't be valid"},
+		{[]string{"-workers", "0"}, nil, "-workers should be at least 1, but was 0"},
+	} {
+		var out strings.Builder
+		err := runKeygen(ctx, tt.args, &out)
+		require.ErrorContains(t, err, tt.msg, tt.args)
+		if tt.err != nil {
+			require.ErrorIs(t, err, tt.err, tt.args)
+		}
+		require.Empty(t, out.String())
+	}
+}
@@ -3,14 +3,16 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"embed"
-	"encoding/xml"
+	"encoding/hex"
 	"fmt"
 	"html"
 	"io"
-	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -34,7 +36,25 @@ var logger = logrus.New()
 func main() {
 	ctx := context.Background()
 
-	if err := run(ctx); err != nil {
+	var err error
+
+	// Without a subcommand, the default is to publish.
+	if len(os.Args) < 2 {
+		err = run(ctx)
+	} else {
+		switch os.Args[1] {
+		case "fetch":
+			err = runFetch(ctx, os.Args[2:], os.Stdout)
+		case "keygen":
+			err = runKeygen(ctx, os.Args[2:], os.Stdout)
+		case "serve-fake":
+			err = runServeFake(ctx, os.Args[2:], os.Stdout)
+		default:
+			abort("unknown subcommand: %q", os.Args[1])
+		}
+	}
+
+	if err != nil {
 		abortErr(err)
 	}
 }
@@ -48,34 +68,62 @@ func abortErr(err error) {
 	abort("error: %v", err)
 }
 
-// Should end with a slash.
-const canonicalURL = "https://brandur.org/"
+// Fetches and parses the feed at the given URL. If cache is non-nil, the
+// request is made conditional on the feed having changed since it was last
+// cached, and a `304 Not Modified` is answered from the cache. The second
+// return value indicates whether the feed changed (always true without a
+// cache).
+func fetchFeed(ctx context.Context, requester *httpRequester, feedURL string, cache *cache, now func() time.Time) (*Feed, bool, error) {
+	headers := http.Header{}
+
+	var cached *feedCacheEntry
+	if cache != nil {
+		cached = cache.GetFeed(feedURL)
+	}
 
-var (
-	aHrefRE     = regexp.MustCompile(`<a href="/`)
-	imgSrcRE    = regexp.MustCompile(`<img src="/`)
-	sourceSrcRE = regexp.MustCompile(`<source src="/`) // in <video> tag
-)
+	if cached != nil {
+		if cached.ETag != "" {
+			headers.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			headers.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
 
-func canonicalizeURLs(content string) string {
-	content = aHrefRE.ReplaceAllString(content, `<a href="`+canonicalURL)
-	content = imgSrcRE.ReplaceAllString(content, `<img src="`+canonicalURL)
-	content = sourceSrcRE.ReplaceAllString(content, `<source src="`+canonicalURL)
-	return content
-}
+	resp, err := requester.requestWithRetries(ctx, http.MethodGet, feedURL, headers, nil)
+	if err != nil {
+		return nil, false, xerrors.Errorf("error getting feed: %w", err)
+	}
+
+	modified := true
+	body, contentType := resp.Body, resp.Header.Get("Content-Type")
 
-func fetchFeed(ctx context.Context, url string) (*Feed, error) {
-	data, err := requestWithRetries(ctx, http.MethodGet, url, nil, nil)
+	if resp.StatusCode == http.StatusNotModified {
+		if cached == nil {
+			return nil, false, xerrors.Errorf("got %d for feed %q, but have no cached copy", resp.StatusCode, feedURL)
+		}
+
+		logger.Infof("Feed %q not modified; using cached copy from %v", feedURL, cached.FetchedAt)
+		modified = false
+		body, contentType = []byte(cached.Body), cached.ContentType
+	}
+
+	feed, err := parseFeed(body, contentType, feedURL)
 	if err != nil {
-		return nil, xerrors.Errorf("error getting feed: %w", err)
+		return nil, false, xerrors.Errorf("error parsing feed %q: %w", feedURL, err)
 	}
 
-	var feed Feed
-	if err := xml.Unmarshal(data, &feed); err != nil {
-		return nil, xerrors.Errorf("error unmarshaling XML feed: %w", err)
+	if cache != nil && modified {
+		cache.SetFeed(feedURL, &feedCacheEntry{
+			Body:         string(body),
+			ContentType:  contentType,
+			ETag:         resp.Header.Get("ETag"),
+			FetchedAt:    now(),
+			LastModified: resp.Header.Get("Last-Modified"),
+		})
 	}
 
-	return &feed, nil
+	return feed, modified, nil
 }
 
 var (
@@ -88,7 +136,13 @@ var (
 )
 
 func minimizeContent(content string) string {
-	content = html.UnescapeString(content)
+	return minimizeWhitespace(html.UnescapeString(content))
+}
+
+// Like minimizeContent, but leaves entities alone. Used for boards rendered
+// from text that's escaped into the layout, where unescaping it would
+// reintroduce markup.
+func minimizeWhitespace(content string) string {
 	content = srcSetRE.ReplaceAllString(content, "")
 	content = strings.ReplaceAll(content, "\n", "")
 	content = twoPlusSpacesRE.ReplaceAllString(content, " ")
@@ -101,108 +155,269 @@ var layoutsFS embed.FS
 
 // Renders the given title, content, and timestamp with the indicated layout,
 // which should be a file in the `layouts/` directory (with extension but
-// without `layouts/` prefix).
+// without `layouts/` prefix), or the path of a template file.
 func renderLayout(layout, title, content string, timestamp time.Time) (string, error) {
-	layoutData, err := layoutsFS.ReadFile("layouts/" + layout)
-	if err != nil {
-		return "", xerrors.Errorf("error reading layout %q: %w", layout, err)
-	}
+	return renderLayoutData(layout, map[string]any{
+		"Content":   content,
+		"Timestamp": timestampTag(timestamp),
+		"Title":     title,
+	})
+}
 
-	tmpl, err := template.New(layout).Parse(string(layoutData))
+// Renders arbitrary data with the indicated layout for layouts that need more
+// than a title and content. Data should include a "Timestamp" produced by
+// timestampTag.
+func renderLayoutData(layout string, data map[string]any) (string, error) {
+	tmpl, err := loadLayout(layout)
 	if err != nil {
-		return "", xerrors.Errorf("error parsing template: %w", err)
+		return "", err
 	}
 
 	var buf bytes.Buffer
 
-	if err := tmpl.Execute(&buf, map[string]any{
-		"Content":   content,
-		"Timestamp": fmt.Sprintf(`<time datetime="%s">`, timestamp.Format(timestampFormat)),
-		"Title":     title,
-	}); err != nil {
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", xerrors.Errorf("error executing template: %w", err)
 	}
 
 	return buf.String(), nil
 }
 
-func requestWithRetries(ctx context.Context, method, url string, headers http.Header, body []byte) ([]byte, error) {
-	var outerErr error
-	var requestNum int
+// Reads and parses a layout, which is one of the built-in layouts if one has
+// the given name, and otherwise a template file at that path.
+func loadLayout(layout string) (*template.Template, error) {
+	var layoutData []byte
+	var err error
+	if isBuiltinLayout(layout) {
+		layoutData, err = layoutsFS.ReadFile("layouts/" + layout)
+	} else {
+		layoutData, err = os.ReadFile(layout)
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("error reading layout %q: %w", layout, err)
+	}
 
-	for {
-		switch {
-		case requestNum > 2:
-			return nil, outerErr
-		case requestNum > 0:
-			time.Sleep(time.Duration(math.Pow(2, float64(requestNum))) * time.Second)
-		}
-		requestNum++
+	tmpl, err := template.New(filepath.Base(layout)).Parse(string(layoutData))
+	if err != nil {
+		return nil, xerrors.Errorf("error parsing template: %w", err)
+	}
 
-		var bodyReader io.Reader
-		if body != nil {
-			bodyReader = bytes.NewReader(body)
-		}
+	return tmpl, nil
+}
 
-		r, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
-		if err != nil {
-			return nil, xerrors.Errorf("error creating new request: %w", err)
-		}
+// Produces the `<time>` element that the spec requires in every board.
+func timestampTag(timestamp time.Time) string {
+	return fmt.Sprintf(`<time datetime="%s">`, timestamp.UTC().Format(timestampFormat))
+}
+
+// response is the result of a successful request made by requestWithRetries.
+// Its body has already been read in full.
+type response struct {
+	Body       []byte
+	Header     http.Header
+	StatusCode int
+}
+
+// statusCodeError is returned by requestWithRetries when a request fails with
+// an unsuccessful status code, so that callers can check for specific ones.
+type statusCodeError struct {
+	StatusCode int
+}
 
-		for key, vals := range headers {
-			for _, val := range vals {
-				r.Header.Add(key, val)
+func (e *statusCodeError) Error() string {
+	return fmt.Sprintf("bad status code during request: %d", e.StatusCode)
+}
+
+// Makes a request, retrying it according to the requester's policy on transient
+// network errors and retryable status codes. Waits between attempts honor a
+// server's Retry-After and are cut short if the context is cancelled.
+func (requester *httpRequester) requestWithRetries(ctx context.Context, method, url string, headers http.Header, body []byte) (*response, error) {
+	policy := requester.RetryPolicy
+
+	var outerErr error
+	var retryAfter time.Duration
+	var waited time.Duration
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if attempt >= policy.MaxAttempts {
+				return nil, outerErr
 			}
-		}
 
-		logger.Infof("Request: %s %v (attempt: %d)", method, url, requestNum-1)
+			delay := policy.Delay(attempt, retryAfter)
+			if waited+delay > policy.MaxTotalWait {
+				logger.Infof("Not retrying %s %v; delay of %v would exceed maximum total wait of %v",
+					method, url, delay, policy.MaxTotalWait)
+				return nil, outerErr
+			}
 
-		resp, err := http.DefaultClient.Do(r)
-		if err != nil {
-			outerErr = xerrors.Errorf("error making request: %w", err)
-			continue
+			logger.Infof("Retrying %s %v in %v", method, url, delay.Round(time.Millisecond))
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, xerrors.Errorf("error waiting to retry request (last error: %v): %w", outerErr, err)
+			}
+			waited += delay
 		}
 
-		defer resp.Body.Close()
+		retryAfter = 0
+
+		logger.Infof("Request: %s %v (attempt: %d)", method, url, attempt)
 
-		respBody, err := io.ReadAll(resp.Body)
+		resp, err := requester.doRequest(ctx, method, url, headers, body)
 		if err != nil {
-			outerErr = xerrors.Errorf("error reading response body: %w", err)
-			continue
+			outerErr = err
+			if ctx.Err() == nil && isTransientNetworkError(err) {
+				continue
+			}
+			return nil, outerErr
 		}
 
-		logger.Infof("Response: %s (body: %q)", resp.Status, stringutil.SampleLong(string(respBody)))
+		logger.Infof("Response: %d (body: %q)", resp.StatusCode, stringutil.SampleLong(string(resp.Body)))
 
 		// Conflict is returned by a Spring '83 implementation in cases where a
 		// newer version of a board has already been posted, so if we encounter
-		// this, consider it a success and stop retrying.
-		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusConflict {
-			err := xerrors.Errorf("bad status code during request: %d", resp.StatusCode)
+		// this, consider it a success and stop retrying. Not Modified is only
+		// ever returned for conditional requests, which callers handle.
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusConflict && resp.StatusCode != http.StatusNotModified {
+			err := &statusCodeError{StatusCode: resp.StatusCode}
 			if shouldRetryStatusCode(resp.StatusCode) {
 				outerErr = err
+				retryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"), requester.now())
 				continue
 			}
 
 			return nil, err
 		}
 
-		return respBody, nil
+		return resp, nil
 	}
 }
 
-func run(ctx context.Context) error {
-	type Config struct {
-		AtomFeedURL      string `env:"ATOM_FEED_URL,required"` // supports multiple comma-separate URLs
-		SpringPrivateKey string `env:"SPRING_PRIVATE_KEY,required"`
-		SpringPublicKey  string `env:"SPRING_PUBLIC_KEY,required"`
-		SpringURL        string `env:"SPRING_URL,required"`
+// Makes a single request with the requester's client and reads its response. The
+// response body is always closed before returning so that connections aren't
+// held open across retries.
+func (requester *httpRequester) doRequest(ctx context.Context, method, url string, headers http.Header, body []byte) (*response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, xerrors.Errorf("error creating new request: %w", err)
+	}
+
+	for key, vals := range headers {
+		for _, val := range vals {
+			r.Header.Add(key, val)
+		}
+	}
+
+	resp, err := requester.Client.Do(r)
+	if err != nil {
+		return nil, xerrors.Errorf("error making request: %w", err)
 	}
+	defer resp.Body.Close()
+
+	respBody, err := readResponseBody(resp)
+	if err != nil {
+		return nil, xerrors.Errorf("error reading response body: %w", err)
+	}
+
+	return &response{Body: respBody, Header: resp.Header, StatusCode: resp.StatusCode}, nil
+}
+
+// Config is the configuration for publishing, which is normally parsed from
+// the environment.
+type Config struct {
+	// Supports multiple comma-separate URLs. Each may be Atom, RSS, JSON
+	// Feed, or an HTML page marked up with h-feed. Either this or
+	// FEEDS_CONFIG_PATH is required.
+	AtomFeedURL string `env:"ATOM_FEED_URL"`
+
+	// Path to a JSON file configuring each feed, including its layout, base
+	// URL, filters, and priority. See feedConfig for options.
+	FeedsConfigPath string `env:"FEEDS_CONFIG_PATH"`
+
+	CachePath    string `env:"CACHE_PATH"`    // caching of feeds and boards is disabled if not set
+	CanonicalURL string `env:"CANONICAL_URL"` // derived from each feed if not set
+
+	// What to do when there are no entries across all feeds: keep the current
+	// board, publish a placeholder board, or fail. The placeholder's content
+	// is HTML that's rendered with the board layout.
+	EmptyFeedPolicy             emptyFeedPolicy `env:"EMPTY_FEED_POLICY" envDefault:"keep"`
+	EmptyFeedPlaceholderContent string          `env:"EMPTY_FEED_PLACEHOLDER_CONTENT"`
+	EmptyFeedPlaceholderTitle   string          `env:"EMPTY_FEED_PLACEHOLDER_TITLE"`
+
+	// Publishes a digest of up to this many of the newest entries instead of
+	// a single entry when set. Fewer are included if they don't all fit.
+	// Entry selection doesn't apply to digests.
+	DigestSize  int    `env:"DIGEST_SIZE"`
+	DigestTitle string `env:"DIGEST_TITLE" envDefault:"Latest"`
+
+	// Strategy for selecting the entry to publish. See entrySelection for
+	// options. Category priorities are formatted like `spring:10,photos:5`.
+	EntrySelection                   entrySelection `env:"ENTRY_SELECTION" envDefault:"newest_published"`
+	EntrySelectionCategoryPriorities string         `env:"ENTRY_SELECTION_CATEGORY_PRIORITIES"`
+	EntrySelectionPinnedID           string         `env:"ENTRY_SELECTION_PINNED_ID"`
+	EntrySelectionWindow             int            `env:"ENTRY_SELECTION_WINDOW" envDefault:"5"`
+
+	// Include and exclude rules for each feed's entries, applied before any
+	// selection. See feedFilters for format.
+	FeedFilters feedFilters `env:"FEED_FILTERS"`
+
+	ForcePublish bool `env:"FORCE_PUBLISH"` // publish even if feeds and board are unchanged
+
+	HTTPRetryAttempts     int           `env:"HTTP_RETRY_ATTEMPTS" envDefault:"3"`
+	HTTPRetryBaseDelay    time.Duration `env:"HTTP_RETRY_BASE_DELAY" envDefault:"2s"`
+	HTTPRetryMaxTotalWait time.Duration `env:"HTTP_RETRY_MAX_TOTAL_WAIT" envDefault:"30s"`
+
+	KeyExpiryWarningDays int    `env:"KEY_EXPIRY_WARNING_DAYS" envDefault:"30"`
+	SpringPrivateKey     string `env:"SPRING_PRIVATE_KEY,required"`
+	SpringPublicKey      string `env:"SPRING_PUBLIC_KEY,required"`
+
+	// Old keys to publish a "this board has moved" board to while rotating
+	// keys. See parseRedirectKeys for format.
+	SpringRedirectKeys string `env:"SPRING_REDIRECT_KEYS"`
+
+	// Supports multiple comma-separated server URLs. Publishing succeeds
+	// if at least SPRING_QUORUM of them accept each board, or all of them
+	// if it's not set.
+	SpringQuorum int    `env:"SPRING_QUORUM"`
+	SpringURL    string `env:"SPRING_URL,required"`
+}
 
+// Runs the default command, which publishes the latest feed entry to a
+// Spring '83 board, with configuration from the environment.
+func run(ctx context.Context) error {
 	config := Config{}
 	if err := env.Parse(&config); err != nil {
 		return xerrors.Errorf("error parsing env config: %w", err)
 	}
 
+	return runWithConfig(ctx, &config, newHTTPClient(), time.Now)
+}
+
+// Publishes the latest feed entry to a Spring '83 board. All HTTP requests
+// are made with httpClient and the current time comes from now, so that both
+// can be swapped out in tests.
+func runWithConfig(ctx context.Context, config *Config, httpClient *http.Client, now func() time.Time) error {
+	if config.HTTPRetryAttempts < 1 {
+		return xerrors.Errorf("HTTP_RETRY_ATTEMPTS should be at least 1, but was %d", config.HTTPRetryAttempts)
+	}
+
+	if config.EmptyFeedPolicy == emptyFeedPolicyPlaceholder && config.EmptyFeedPlaceholderContent == "" {
+		return xerrors.Errorf("EMPTY_FEED_PLACEHOLDER_CONTENT is required with EMPTY_FEED_POLICY=%s", emptyFeedPolicyPlaceholder)
+	}
+
+	requester := &httpRequester{
+		Client: httpClient,
+		RetryPolicy: retryPolicy{
+			MaxAttempts:  config.HTTPRetryAttempts,
+			BaseDelay:    config.HTTPRetryBaseDelay,
+			MaxTotalWait: config.HTTPRetryMaxTotalWait,
+		},
+		Now: now,
+	}
+
 	keyPair, err := ParseKeyPairUnchecked(config.SpringPrivateKey)
 	if err != nil {
 		return err
@@ -214,8 +429,84 @@ func run(ctx context.Context) error {
 		return xerrors.Errorf("SPRING_PUBLIC_KEY doesn't match the public key portion of SPRING_PRIVATE_KEY")
 	}
 
+	redirectKeys, err := parseRedirectKeys(config.SpringRedirectKeys, keyPair)
+	if err != nil {
+		return err
+	}
+
+	keys := append([]*publishKey{{KeyPair: keyPair, Role: keyRolePrimary}}, redirectKeys...)
+
+	for _, key := range keys {
+		if err := validateKey(key.KeyPair, now(), time.Duration(config.KeyExpiryWarningDays)*24*time.Hour); err != nil {
+			return err
+		}
+	}
+
+	springURLs := strings.Split(config.SpringURL, ",")
+	for i, springURL := range springURLs {
+		springURLs[i] = strings.TrimRight(strings.TrimSpace(springURL), "/")
+	}
+
+	quorum := config.SpringQuorum
+	switch {
+	case quorum == 0:
+		quorum = len(springURLs)
+	case quorum < 0 || quorum > len(springURLs):
+		return xerrors.Errorf("SPRING_QUORUM should be between 1 and the number of servers (%d), but was %d",
+			len(springURLs), quorum)
+	}
+
+	feeds, err := loadFeedConfigs(config)
+	if err != nil {
+		return err
+	}
+
+	var canonicalURL *url.URL
+	if config.CanonicalURL != "" {
+		canonicalURL, err = url.Parse(config.CanonicalURL)
+		if err != nil {
+			return xerrors.Errorf("error parsing CANONICAL_URL: %w", err)
+		}
+
+		if !canonicalURL.IsAbs() {
+			return xerrors.Errorf("CANONICAL_URL should be an absolute URL, but was %q", config.CanonicalURL)
+		}
+	}
+
+	var cache *cache
+	if config.CachePath != "" {
+		cache, err = loadCache(config.CachePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	categoryPriorities, err := parseCategoryPriorities(config.EntrySelectionCategoryPriorities)
+	if err != nil {
+		return xerrors.Errorf("error parsing ENTRY_SELECTION_CATEGORY_PRIORITIES: %w", err)
+	}
+
+	feedPriorities := slices.IndexFunc(feeds, func(feed *feedConfig) bool { return feed.Priority != 0 }) != -1
+
+	selector, err := newEntrySelector(config.EntrySelection, &entrySelectorConfig{
+		CategoryPriorities: categoryPriorities,
+		FeedPriorities:     feedPriorities,
+		PinnedID:           config.EntrySelectionPinnedID,
+		Window:             config.EntrySelectionWindow,
+	}, cache)
+	if err != nil {
+		return err
+	}
+
+	// Rather than silently ignoring them.
+	if feedPriorities && (selector.Name != entrySelectionCategoryPriority || config.DigestSize > 0) {
+		return xerrors.Errorf("feed priorities only apply with ENTRY_SELECTION=%s and without DIGEST_SIZE",
+			entrySelectionCategoryPriority)
+	}
+
 	var entries []*Entry
 	var entriesMut sync.Mutex
+	var anyModified bool
 
 	// Nested so the errgroup's context isn't retained (it's cancelled after
 	// use).
@@ -223,23 +514,46 @@ func run(ctx context.Context) error {
 		errGroup, ctx := errgroup.WithContext(ctx)
 		errGroup.SetLimit(10)
 
-		feedURLs := strings.Split(config.AtomFeedURL, ",")
-		for i := range feedURLs {
-			feedURL := feedURLs[i]
+		for i := range feeds {
+			feedConfig := feeds[i]
+			feedURL := feedConfig.URL
 
 			errGroup.Go(func() error {
-				feed, err := fetchFeed(ctx, feedURL)
+				feed, modified, err := fetchFeed(ctx, requester, feedURL, cache, now)
 				if err != nil {
 					return err
 				}
 
-				if len(feed.Entries) < 1 {
-					logger.Infof("No entries in feed; taking no action")
+				baseURL := canonicalURL
+				if feedConfig.baseURL != nil {
+					baseURL = feedConfig.baseURL
+				}
+
+				if err := feed.AssignBaseURLs(feedURL, baseURL); err != nil {
+					return err
+				}
+
+				for _, entry := range feed.Entries {
+					entry.FeedPriority = feedConfig.Priority
+					entry.Layout = feedConfig.Layout
+				}
+
+				entriesMut.Lock()
+				anyModified = anyModified || modified
+				entriesMut.Unlock()
+
+				feedEntries := filterEntries(feed.Entries, feedConfig.Filter)
+				if numFiltered := len(feed.Entries) - len(feedEntries); numFiltered > 0 {
+					logger.Infof("Filtered out %d of %d entries in feed %q", numFiltered, len(feed.Entries), feedURL)
+				}
+
+				if len(feedEntries) < 1 {
+					logger.Infof("No entries in feed %q", feedURL)
 					return nil
 				}
 
 				entriesMut.Lock()
-				entries = append(entries, feed.Entries...)
+				entries = append(entries, feedEntries...)
 				entriesMut.Unlock()
 
 				return nil
@@ -251,52 +565,362 @@ func run(ctx context.Context) error {
 		}
 	}
 
-	slices.SortFunc(entries, sortEntriesDesc)
+	entries = dedupeEntries(entries)
+
+	// Only possible with a cache. The board would be the same as what was
+	// published last time, unless the selection strategy rotates entries, or
+	// a server or key has been added since and hasn't had a board yet.
+	if !anyModified && !config.ForcePublish && !selector.Rotates && allBoardsCached(cache, keys, springURLs) {
+		logger.Infof("No feeds modified since last run; skipping publish")
+		return nil
+	}
+
+	var board *renderedBoard
+	var entry *Entry
+
+	switch {
+	case len(entries) > 0 && config.DigestSize > 0:
+		slices.SortFunc(entries, sortEntriesDesc)
+
+		var n int
+		board, n, err = renderDigestBoard(config.DigestTitle, entries, config.DigestSize)
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("Digest board is %d bytes with %d of up to %d entries", len(board.Content), n, config.DigestSize)
+
+	case len(entries) > 0:
+		slices.SortFunc(entries, sortEntriesDesc)
+
+		selected, err := selector.Select(entries)
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("Selected entry %q (ID: %s) with strategy %s", selected.Title, selected.ID, selector.Name)
+
+		// Copied so that the board's timestamp can be set without modifying
+		// the entry.
+		selectedCopy := *selected
+		selectedCopy.Published = selector.Timestamp(selected, now())
+		entry = &selectedCopy
+
+	default:
+		switch config.EmptyFeedPolicy {
+		case emptyFeedPolicyFail:
+			return xerrors.Errorf("refusing to publish (EMPTY_FEED_POLICY=%s): %w", config.EmptyFeedPolicy, ErrNoEntries)
+
+		case emptyFeedPolicyPlaceholder:
+			logger.Warnf("No entries in any feed; publishing placeholder board (EMPTY_FEED_POLICY=%s)", config.EmptyFeedPolicy)
+			entry = placeholderEntry(config.EmptyFeedPlaceholderTitle, config.EmptyFeedPlaceholderContent, now())
+
+		case emptyFeedPolicyKeep, "":
+			logger.Warnf("No entries in any feed; keeping current board (EMPTY_FEED_POLICY=%s)", emptyFeedPolicyKeep)
+
+			// There's nothing to retry, so remember the feeds as they are.
+			if cache != nil {
+				return cache.Save()
+			}
+			return nil
+
+		default:
+			return xerrors.Errorf("unknown empty feed policy %q", config.EmptyFeedPolicy)
+		}
+	}
+
+	if board == nil {
+		rendered, fallback, err := renderBoard(entry)
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("Raw content is %d bytes; %d bytes after layout, canonicalization, and minification (fallback: %s)",
+			len([]byte(entryContent(entry))),
+			len(rendered),
+			fallback,
+		)
 
-	if err := updateSpring(ctx, keyPair, config.SpringURL, entries[0]); err != nil {
+		board = &renderedBoard{Content: rendered, Timestamp: entry.Published}
+	}
+
+	if _, err := updateSpring(ctx, requester, keys, springURLs, board, cache, config.ForcePublish, quorum, now); err != nil {
 		return err
 	}
 
+	// Saved only after a successful publish so that if publishing fails, the
+	// next run will see feeds as modified and try again.
+	if cache != nil {
+		if err := cache.Save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Checks that a keypair is Spring '83 compliant and currently valid, because a
+// server will reject anything published with it otherwise. Logs a warning if
+// the key expires within warningWindow so that there's time to rotate it.
+func validateKey(keyPair *KeyPair, now time.Time, warningWindow time.Duration) error {
+	// The test key would fail validation anyway, but with a confusing error
+	// about its validity period.
+	if keyPair.IsTestKey() {
+		return xerrors.Errorf("refusing to publish with key %s: %w", keyPair.PublicKey, ErrKeyTest)
+	}
+
+	key, err := ParseKey(keyPair.PublicKey, now)
+	if err != nil {
+		return xerrors.Errorf("refusing to publish with key %s: %w", keyPair.PublicKey, err)
+	}
+
+	if remaining := key.ExpiresAt.Sub(now); remaining < warningWindow {
+		logger.Warnf("Key %s expires in %d day(s) at %v; rotate it soon",
+			keyPair.PublicKey, int(remaining.Hours()/24), key.ExpiresAt)
+	}
+
 	return nil
 }
 
 func shouldRetryStatusCode(statusCode int) bool {
 	switch statusCode {
-	case http.StatusTooManyRequests:
-		fallthrough
-	case http.StatusInternalServerError:
+	case http.StatusBadGateway,
+		http.StatusGatewayTimeout,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusTooManyRequests:
 		return true
 	}
 
 	return false
 }
 
-func updateSpring(ctx context.Context, keyPair *KeyPair, springURL string, entry *Entry) error {
-	rendered, err := renderLayout("sequences.tmpl.html", entry.Title, entry.Content.Content, entry.Published)
+// publishOutcome is the outcome of publishing a board to a single server.
+type publishOutcome string
+
+const (
+	// publishOutcomeConflict indicates that the server already had a newer
+	// board for the key, so ours wasn't published.
+	publishOutcomeConflict publishOutcome = "conflict"
+
+	// publishOutcomeCreated indicates that the board was published.
+	publishOutcomeCreated publishOutcome = "created"
+
+	// publishOutcomeFailed indicates that publishing failed, with the reason
+	// in publishResult.Err.
+	publishOutcomeFailed publishOutcome = "failed"
+
+	// publishOutcomeUnchanged indicates that the board was identical to the
+	// one last published to the server, so publishing was skipped.
+	publishOutcomeUnchanged publishOutcome = "unchanged"
+)
+
+// Accepted is whether the server is known to hold this board or a newer one
+// of ours, and therefore counts towards a quorum.
+func (o publishOutcome) Accepted() bool {
+	return o != publishOutcomeFailed
+}
+
+// publishResult is the result of publishing a board for a key to a single
+// server.
+type publishResult struct {
+	Err       error
+	Outcome   publishOutcome
+	PublicKey string
+	SpringURL string
+}
+
+// Renders boards for each of the given keys and publishes them to each of the
+// given Spring '83 servers concurrently. Primary keys get the given board, and
+// redirect keys get a board pointing to the key they redirect to on the same
+// server.
+//
+// A failure on one server doesn't stop publishing to the others. Results for
+// every key and server are returned, and an error is returned if, for any
+// key, fewer than quorum servers accepted its board.
+func updateSpring(ctx context.Context, requester *httpRequester, keys []*publishKey, springURLs []string, board *renderedBoard, cache *cache, force bool, quorum int, now func() time.Time) ([]*publishResult, error) {
+	var results []*publishResult
+	var resultsMut sync.Mutex
+
+	errGroup, ctx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(10)
+
+	for i := range keys {
+		key := keys[i]
+
+		for j := range springURLs {
+			springURL := springURLs[j]
+
+			errGroup.Go(func() error {
+				// Rendering a redirect board involves a request to the server,
+				// so its failure counts as a failure of the server too.
+				var outcome publishOutcome
+				rendered, err := renderBoardForKey(ctx, requester, key, springURL, board, now)
+				if err == nil {
+					outcome, err = publishBoard(ctx, requester, key.KeyPair, springURL, rendered, cache, force, now)
+				}
+				if err != nil {
+					outcome = publishOutcomeFailed
+				}
+
+				resultsMut.Lock()
+				results = append(results, &publishResult{
+					Err:       err,
+					Outcome:   outcome,
+					PublicKey: key.KeyPair.PublicKey,
+					SpringURL: springURL,
+				})
+				resultsMut.Unlock()
+
+				// Not returned to the group so that a failure on one server
+				// doesn't cancel publishing to the others.
+				return nil
+			})
+		}
+	}
+
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+
+	// Stable order for the report since publishing happened concurrently.
+	slices.SortFunc(results, func(a, b *publishResult) bool {
+		if a.PublicKey != b.PublicKey {
+			return a.PublicKey < b.PublicKey
+		}
+		return a.SpringURL < b.SpringURL
+	})
+
+	accepted := make(map[string]int)
+	for _, result := range results {
+		if result.Err != nil {
+			logger.Errorf("Publish of key %s to %s: %s (%v)", result.PublicKey, result.SpringURL, result.Outcome, result.Err)
+		} else {
+			logger.Infof("Publish of key %s to %s: %s", result.PublicKey, result.SpringURL, result.Outcome)
+		}
+
+		if result.Outcome.Accepted() {
+			accepted[result.PublicKey]++
+		}
+	}
+
+	for _, key := range keys {
+		if n := accepted[key.KeyPair.PublicKey]; n < quorum {
+			return results, xerrors.Errorf("board for key %s accepted by only %d of %d server(s), but quorum is %d",
+				key.KeyPair.PublicKey, n, len(springURLs), quorum)
+		}
+	}
+
+	return results, nil
+}
+
+// Whether cache has a board published to every server for every key. cache
+// may be nil.
+func allBoardsCached(cache *cache, keys []*publishKey, springURLs []string) bool {
+	if cache == nil {
+		return false
+	}
+
+	for _, key := range keys {
+		for _, springURL := range springURLs {
+			if cache.GetBoard(springURL, key.KeyPair.PublicKey) == nil {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Renders the board for a key according to its role. A redirect board's
+// timestamp depends on the board that's live for the key, which is fetched
+// from the server.
+func renderBoardForKey(ctx context.Context, requester *httpRequester, key *publishKey, springURL string, board *renderedBoard, now func() time.Time) (string, error) {
+	switch key.Role {
+	case keyRolePrimary:
+		return board.Content, nil
+
+	case keyRoleRedirect:
+		live, err := fetchBoard(ctx, requester, springURL, &key.KeyPair.Key)
+		if err != nil && !xerrors.Is(err, ErrBoardNotFound) {
+			return "", err
+		}
+
+		timestamp, err := redirectBoardTimestamp(live, springURL, key.RedirectTo, board.Timestamp, now())
+		if err != nil {
+			return "", err
+		}
+
+		return renderRedirectBoard(springURL, key.RedirectTo, timestamp)
+	}
+
+	return "", xerrors.Errorf("unknown role %q for key %s", key.Role, key.KeyPair.PublicKey)
+}
+
+// Publishes a rendered board for a key to a Spring '83 server. If cache is
+// non-nil, publishing is skipped when the board is byte-for-byte identical to
+// the one last published to the same server and key, unless force is set.
+func publishBoard(ctx context.Context, requester *httpRequester, keyPair *KeyPair, springURL, rendered string, cache *cache, force bool, now func() time.Time) (publishOutcome, error) {
+	sum := sha256.Sum256([]byte(rendered))
+	boardSHA256 := hex.EncodeToString(sum[:])
+
+	if cache != nil && !force {
+		if cached := cache.GetBoard(springURL, keyPair.PublicKey); cached != nil && cached.SHA256 == boardSHA256 {
+			logger.Infof("Board for key %s on %s unchanged since it was published at %v (SHA-256: %s); skipping publish",
+				keyPair.PublicKey, springURL, cached.PublishedAt, boardSHA256)
+			return publishOutcomeUnchanged, nil
+		}
+	}
+
+	timestamp, err := parseBoardTimestamp([]byte(rendered))
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	rendered = canonicalizeURLs(rendered)
-	rendered = minimizeContent(rendered)
+	// The server would reject an older board anyway, but check first to
+	// produce a clearer message, and to make sure that what's there really is
+	// ours before deferring to it.
+	liveBoard, err := fetchBoard(ctx, requester, springURL, &keyPair.Key)
+	if err != nil && !xerrors.Is(err, ErrBoardNotFound) {
+		return "", err
+	}
 
-	logger.Infof("Raw content is %d bytes; %d bytes after layout, canonicalization, and minification",
-		len([]byte(entry.Content.Content)),
-		len(rendered),
-	)
+	if liveBoard != nil && liveBoard.Verified && liveBoard.Timestamp.After(timestamp) {
+		logger.Infof("Live board for key %s on %s has newer timestamp %v than %v; refusing to overwrite it",
+			keyPair.PublicKey, springURL, liveBoard.Timestamp, timestamp)
+		return publishOutcomeConflict, nil
+	}
 
-	respBody, err := requestWithRetries(ctx, http.MethodPut, springURL+"/"+keyPair.PublicKey, http.Header{
+	resp, err := requester.requestWithRetries(ctx, http.MethodPut, springURL+"/"+keyPair.PublicKey, http.Header{
 		"Spring-Signature": []string{keyPair.SignHex([]byte(rendered))},
 	}, []byte(rendered))
 	if err != nil {
-		return xerrors.Errorf("error updating board: %w", err)
+		return "", xerrors.Errorf("error updating board: %w", err)
+	}
+
+	// Raced with another publisher, or the server's board is newer but
+	// wasn't verifiable above.
+	if resp.StatusCode == http.StatusConflict {
+		logger.Infof("Server %s has a newer board for key %s than %v (resp body: %q)",
+			springURL, keyPair.PublicKey, timestamp, string(resp.Body))
+		return publishOutcomeConflict, nil
 	}
 
-	logger.Infof("Successfully published entry %q with timestamp %v (resp body: %q)",
-		entry.Title,
-		entry.Published,
-		string(respBody),
+	logger.Infof("Successfully published board for key %s to %s with timestamp %v (resp body: %q)",
+		keyPair.PublicKey,
+		springURL,
+		timestamp,
+		string(resp.Body),
 	)
 
-	return err
+	confirmBoard(ctx, requester, springURL, &keyPair.Key, []byte(rendered))
+
+	if cache != nil {
+		cache.SetBoard(springURL, keyPair.PublicKey, &boardCacheEntry{
+			PublishedAt: now(),
+			SHA256:      boardSHA256,
+		})
+	}
+
+	return publishOutcomeCreated, nil
 }
@@ -1,15 +1,73 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/brandur/neospring-bridge/internal/springfake"
 )
 
-func TestCanonicalURLs(t *testing.T) {
-	require.Equal(t, sampleContentCanonicalized, canonicalizeURLs(sampleContent))
+func TestFetchFeed(t *testing.T) {
+	ctx := context.Background()
+
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Wed, 09 Nov 2022 10:11:12 GMT")
+		_, _ = w.Write([]byte(sampleAtom))
+	}))
+	defer server.Close()
+
+	t.Run("NoCache", func(t *testing.T) {
+		feed, modified, err := fetchFeed(ctx, newHTTPRequester(), server.URL, nil, time.Now)
+		require.NoError(t, err)
+		require.True(t, modified)
+		require.Len(t, feed.Entries, 1)
+	})
+
+	t.Run("ConditionalGet", func(t *testing.T) {
+		requests = nil
+
+		cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"))
+		require.NoError(t, err)
+
+		feed, modified, err := fetchFeed(ctx, newHTTPRequester(), server.URL, cache, time.Now)
+		require.NoError(t, err)
+		require.True(t, modified)
+		require.Len(t, feed.Entries, 1)
+		require.Equal(t, `"v1"`, cache.GetFeed(server.URL).ETag)
+
+		feed, modified, err = fetchFeed(ctx, newHTTPRequester(), server.URL, cache, time.Now)
+		require.NoError(t, err)
+		require.False(t, modified)
+		require.Len(t, feed.Entries, 1)
+
+		require.Len(t, requests, 2)
+		require.Equal(t, "", requests[0].Header.Get("If-None-Match"))
+		require.Equal(t, `"v1"`, requests[1].Header.Get("If-None-Match"))
+		require.Equal(t, "Wed, 09 Nov 2022 10:11:12 GMT", requests[1].Header.Get("If-Modified-Since"))
+	})
 }
 
 func TestMinimizeContent(t *testing.T) {
@@ -20,11 +78,748 @@ func TestRenderLayout(t *testing.T) {
 	// Just a very basic check that things work without erroring
 	_, err := renderLayout("sequences.tmpl.html", "a title", "some content", time.Now())
 	require.NoError(t, err)
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "custom.tmpl.html")
+		require.NoError(t, os.WriteFile(path, []byte("{{.Timestamp}}<h2>{{.Title}}</h2>{{.Content}}"), 0o600))
+
+		rendered, err := renderLayout(path, "a title", "some content", time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		require.Equal(t, `<time datetime="2022-11-20T00:00:00Z"><h2>a title</h2>some content`, rendered)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := renderLayout("missing.tmpl.html", "a title", "some content", time.Now())
+		require.ErrorContains(t, err, `error reading layout "missing.tmpl.html"`)
+	})
+}
+
+// Rewrites golden files under testdata/ with actual results instead of
+// comparing against them. Run with `go test . -update`.
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/")
+
+// Runs a full publish with feeds served from testdata/feeds and boards
+// published to a fake Spring '83 server, and checks the exact board that was
+// published against the golden file in testdata/boards.
+func TestRunWithConfig(t *testing.T) {
+	ctx := context.Background()
+	keyPair := MustParseKeyPairUnchecked(samplePrivateKey)
+	now := time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC)
+
+	feedServer := httptest.NewServer(http.FileServer(http.Dir("testdata/feeds")))
+	defer feedServer.Close()
+
+	type testCase struct {
+		name      string
+		feeds     []string
+		configure func(t *testing.T, config *Config) // optional
+		golden    string                             // empty if nothing should be published
+		err       string
+	}
+
+	runTestCase := func(t *testing.T, tt testCase) {
+		t.Helper()
+
+		fake := springfake.NewServer()
+		fake.Now = func() time.Time { return now }
+
+		springServer := httptest.NewServer(fake)
+		defer springServer.Close()
+
+		feedURLs := make([]string, len(tt.feeds))
+		for i, feed := range tt.feeds {
+			feedURLs[i] = feedServer.URL + "/" + feed
+		}
+
+		config := &Config{
+			AtomFeedURL:          strings.Join(feedURLs, ","),
+			HTTPRetryAttempts:    1,
+			KeyExpiryWarningDays: 30,
+			SpringPrivateKey:     samplePrivateKey,
+			SpringPublicKey:      samplePublicKey,
+			SpringURL:            springServer.URL,
+		}
+		if tt.configure != nil {
+			tt.configure(t, config)
+		}
+
+		err := runWithConfig(ctx, config, http.DefaultClient, func() time.Time { return now })
+		if tt.err != "" {
+			require.ErrorContains(t, err, tt.err)
+		} else {
+			require.NoError(t, err)
+		}
+
+		board := fake.Board(keyPair.PublicKey)
+		if tt.golden == "" {
+			require.Nil(t, board)
+			return
+		}
+
+		require.NotNil(t, board)
+		require.Equal(t, keyPair.SignHex(board.Content), board.Signature)
+		requireGolden(t, filepath.Join("testdata", "boards", tt.golden), board.Content)
+	}
+
+	for _, tt := range []testCase{
+		{
+			name:   "SingleFeed",
+			feeds:  []string{"sequences.atom"},
+			golden: "sequences.html",
+		},
+		{
+			// The newest entry across all feeds wins.
+			name:   "MultipleFeeds",
+			feeds:  []string{"sequences.atom", "atoms.atom"},
+			golden: "atoms.html",
+		},
+		{
+			name:   "EmptyFeed",
+			feeds:  []string{"empty.atom", "sequences.atom"},
+			golden: "sequences.html",
+		},
+		{
+			name:  "NewestUpdated",
+			feeds: []string{"sequences.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.EntrySelection = entrySelectionNewestUpdated
+			},
+			golden: "sequences_newest_updated.html",
+		},
+		{
+			name:  "Pinned",
+			feeds: []string{"sequences.atom", "atoms.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.EntrySelection = entrySelectionPinned
+				config.EntrySelectionPinnedID = "tag:example.com,2022:sequences/029"
+			},
+			golden: "sequences_pinned.html",
+		},
+		{
+			name:  "Digest",
+			feeds: []string{"sequences.atom", "atoms.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.DigestSize = 5
+				config.DigestTitle = "Latest"
+			},
+			golden: "digest.html",
+		},
+		{
+			// The syndicated copy of the newest entry has the same link and
+			// was updated more recently, so it wins.
+			name:   "Duplicates",
+			feeds:  []string{"sequences.atom", "syndicated.atom"},
+			golden: "syndicated.html",
+		},
+		{
+			// Filters only apply to the feed they're configured for.
+			name:  "Filtered",
+			feeds: []string{"sequences.atom", "atoms.atom"},
+			configure: func(t *testing.T, config *Config) {
+				feedURLs := strings.Split(config.AtomFeedURL, ",")
+				config.FeedFilters = feedFilters{
+					feedURLs[1]: {ExcludeTitles: []*titlePattern{mustTitlePattern(t, "^Atom ")}},
+				}
+			},
+			golden: "sequences.html",
+		},
+		{
+			name:  "FilteredUnknownFeed",
+			feeds: []string{"sequences.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.FeedFilters = feedFilters{"https://example.com/other.atom": {}}
+			},
+			err: "isn't in ATOM_FEED_URL",
+		},
+		{
+			name:  "FeedsConfig",
+			feeds: []string{"sequences.atom", "atoms.atom"},
+			configure: func(t *testing.T, config *Config) {
+				feedURLs := strings.Split(config.AtomFeedURL, ",")
+				writeFeedsConfig(t, config, `{"feeds": [
+					{"url": %q},
+					{"url": %q, "base_url": "https://example.org/", "layout": "atoms.tmpl.html"}
+				]}`, feedURLs[0], feedURLs[1])
+			},
+			golden: "atoms_feeds_config.html",
+		},
+		{
+			// Without priorities, the atom would win as the newest entry.
+			name:  "FeedsConfigPriority",
+			feeds: []string{"sequences.atom", "atoms.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.EntrySelection = entrySelectionCategoryPriority
+
+				feedURLs := strings.Split(config.AtomFeedURL, ",")
+				writeFeedsConfig(t, config, `{"feeds": [
+					{"url": %q, "priority": 1},
+					{"url": %q}
+				]}`, feedURLs[0], feedURLs[1])
+			},
+			golden: "sequences_priority.html",
+		},
+		{
+			name:  "FeedsConfigPriorityIgnored",
+			feeds: []string{"sequences.atom", "atoms.atom"},
+			configure: func(t *testing.T, config *Config) {
+				feedURLs := strings.Split(config.AtomFeedURL, ",")
+				writeFeedsConfig(t, config, `{"feeds": [
+					{"url": %q, "priority": 1},
+					{"url": %q}
+				]}`, feedURLs[0], feedURLs[1])
+			},
+			err: "feed priorities only apply with ENTRY_SELECTION=category_priority",
+		},
+		{
+			name:  "NoFeeds",
+			feeds: []string{"sequences.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.AtomFeedURL = ""
+			},
+			err: "one of ATOM_FEED_URL or FEEDS_CONFIG_PATH is required",
+		},
+		{
+			name:  "AllFeedsEmpty",
+			feeds: []string{"empty.atom", "empty.atom"},
+		},
+		{
+			name:  "AllFeedsEmptyFail",
+			feeds: []string{"empty.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.EmptyFeedPolicy = emptyFeedPolicyFail
+			},
+			err: ErrNoEntries.Error(),
+		},
+		{
+			name:  "AllFeedsEmptyPlaceholder",
+			feeds: []string{"empty.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.EmptyFeedPolicy = emptyFeedPolicyPlaceholder
+				config.EmptyFeedPlaceholderContent = "<p>Nothing here yet.</p>"
+				config.EmptyFeedPlaceholderTitle = "Check back soon"
+			},
+			golden: "placeholder.html",
+		},
+		{
+			name:  "AllFeedsEmptyPlaceholderMissingContent",
+			feeds: []string{"empty.atom"},
+			configure: func(t *testing.T, config *Config) {
+				config.EmptyFeedPolicy = emptyFeedPolicyPlaceholder
+			},
+			err: "EMPTY_FEED_PLACEHOLDER_CONTENT is required",
+		},
+		{
+			name:  "FeedError",
+			feeds: []string{"sequences.atom", "missing.atom"},
+			err:   "error fetching feeds",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			runTestCase(t, tt)
+		})
+	}
+}
+
+// A run with a cache skips publishing when feeds are unchanged, but not to a
+// server that's been added since the last run.
+func TestRunWithConfigAddedServer(t *testing.T) {
+	ctx := context.Background()
+	keyPair := MustParseKeyPairUnchecked(samplePrivateKey)
+	now := time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC)
+
+	feedServer := httptest.NewServer(http.FileServer(http.Dir("testdata/feeds")))
+	defer feedServer.Close()
+
+	newSpringServer := func(t *testing.T) (*springfake.Server, string) {
+		t.Helper()
+
+		fake := springfake.NewServer()
+		fake.Now = func() time.Time { return now }
+
+		server := httptest.NewServer(fake)
+		t.Cleanup(server.Close)
+		return fake, server.URL
+	}
+
+	fake1, springURL1 := newSpringServer(t)
+	fake2, springURL2 := newSpringServer(t)
+
+	config := &Config{
+		AtomFeedURL:          feedServer.URL + "/sequences.atom",
+		CachePath:            filepath.Join(t.TempDir(), "cache.json"),
+		HTTPRetryAttempts:    1,
+		KeyExpiryWarningDays: 30,
+		SpringPrivateKey:     samplePrivateKey,
+		SpringPublicKey:      samplePublicKey,
+		SpringURL:            springURL1,
+	}
+
+	require.NoError(t, runWithConfig(ctx, config, http.DefaultClient, func() time.Time { return now }))
+	require.NotNil(t, fake1.Board(keyPair.PublicKey))
+
+	// Cached times come from the injected clock.
+	cache, err := loadCache(config.CachePath)
+	require.NoError(t, err)
+	require.Equal(t, now, cache.GetFeed(config.AtomFeedURL).FetchedAt)
+	require.Equal(t, now, cache.GetBoard(springURL1, keyPair.PublicKey).PublishedAt)
+
+	config.SpringURL = springURL1 + "," + springURL2
+	require.NoError(t, runWithConfig(ctx, config, http.DefaultClient, func() time.Time { return now }))
+	require.NotNil(t, fake2.Board(keyPair.PublicKey))
+	require.Equal(t, fake1.Board(keyPair.PublicKey).Content, fake2.Board(keyPair.PublicKey).Content)
+}
+
+// Writes a feeds config file formatted from format and args, and configures
+// the run to use it instead of ATOM_FEED_URL.
+func writeFeedsConfig(t *testing.T, config *Config, format string, args ...any) {
+	t.Helper()
+
+	config.AtomFeedURL = ""
+	config.FeedsConfigPath = writeFeedsConfigFile(t, fmt.Sprintf(format, args...))
+}
+
+// Checks that actual matches the contents of the golden file at path, or
+// writes actual to it if the `-update` flag is set.
+func requireGolden(t *testing.T, path string, actual []byte) {
+	t.Helper()
+
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, actual, 0o600))
+	}
+
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual))
+}
+
+// Renders a single entry board for tests that publish one.
+func mustRenderBoard(t *testing.T, entry *Entry) *renderedBoard {
+	t.Helper()
+
+	rendered, _, err := renderBoard(entry)
+	require.NoError(t, err)
+	return &renderedBoard{Content: rendered, Timestamp: entry.Published}
+}
+
+func TestUpdateSpring(t *testing.T) {
+	ctx := context.Background()
+	keyPair := MustParseKeyPairUnchecked(samplePrivateKey)
+	keys := []*publishKey{{KeyPair: keyPair, Role: keyRolePrimary}}
+
+	var (
+		board     []byte
+		puts      int
+		signature string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if board == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Spring-Signature", signature)
+			_, _ = w.Write(board)
+
+		case http.MethodPut:
+			puts++
+			board, _ = io.ReadAll(r.Body)
+			signature = r.Header.Get("Spring-Signature")
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	entry := &Entry{
+		Title:     "a title",
+		Content:   &EntryContent{Content: "<p>some content</p>"},
+		Published: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+	}
+
+	publish := func(entry *Entry, cache *cache, force bool) []*publishResult {
+		results, err := updateSpring(ctx, newHTTPRequester(), keys, []string{server.URL}, mustRenderBoard(t, entry), cache, force, 1, time.Now)
+		require.NoError(t, err)
+		return results
+	}
+
+	t.Run("NoCache", func(t *testing.T) {
+		puts = 0
+
+		publish(entry, nil, false)
+		publish(entry, nil, false)
+		require.Equal(t, 2, puts)
+	})
+
+	t.Run("SkipsUnchanged", func(t *testing.T) {
+		puts = 0
+
+		cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"))
+		require.NoError(t, err)
+
+		publish(entry, cache, false)
+		require.NotNil(t, cache.GetBoard(server.URL, keyPair.PublicKey))
+		require.Equal(t, 1, puts)
+
+		results := publish(entry, cache, false)
+		require.Equal(t, 1, puts)
+		require.Equal(t, publishOutcomeUnchanged, results[0].Outcome)
+
+		// Forced.
+		publish(entry, cache, true)
+		require.Equal(t, 2, puts)
+
+		// Changed content.
+		changedEntry := *entry
+		changedEntry.Content = &EntryContent{Content: "<p>changed content</p>"}
+		publish(&changedEntry, cache, false)
+		require.Equal(t, 3, puts)
+	})
+
+	t.Run("RefusesToOverwriteNewer", func(t *testing.T) {
+		puts = 0
+
+		newerEntry := *entry
+		newerEntry.Published = entry.Published.Add(1 * time.Hour)
+		publish(&newerEntry, nil, false)
+		require.Equal(t, 1, puts)
+
+		results := publish(entry, nil, false)
+		require.Equal(t, 1, puts)
+		require.Equal(t, publishOutcomeConflict, results[0].Outcome)
+	})
+}
+
+func TestUpdateSpringUnconfirmed(t *testing.T) {
+	ctx := context.Background()
+	keyPair := MustParseKeyPairUnchecked(samplePrivateKey)
+	keys := []*publishKey{{KeyPair: keyPair, Role: keyRolePrimary}}
+
+	// Accepts boards, but the confirming fetch afterwards fails with a 404.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"))
+	require.NoError(t, err)
+
+	entry := &Entry{
+		Title:     "a title",
+		Content:   &EntryContent{Content: "<p>some content</p>"},
+		Published: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+	}
+
+	// Failing to confirm the board doesn't fail the publish.
+	results, err := updateSpring(ctx, newHTTPRequester(), keys, []string{server.URL}, mustRenderBoard(t, entry), cache, false, 1, time.Now)
+	require.NoError(t, err)
+	require.Equal(t, publishOutcomeCreated, results[0].Outcome)
+	require.NotNil(t, cache.GetBoard(server.URL, keyPair.PublicKey))
+}
+
+func TestUpdateSpringFakeServer(t *testing.T) {
+	ctx := context.Background()
+	keyPair := MustParseKeyPairUnchecked(samplePrivateKey)
+	keys := []*publishKey{{KeyPair: keyPair, Role: keyRolePrimary}}
+
+	fake := springfake.NewServer()
+	fake.Now = func() time.Time { return time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC) }
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	entry := &Entry{
+		Title:     "a title",
+		Content:   &EntryContent{Content: "<p>some content</p>"},
+		Published: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+	}
+
+	results, err := updateSpring(ctx, newHTTPRequester(), keys, []string{server.URL}, mustRenderBoard(t, entry), nil, false, 1, time.Now)
+	require.NoError(t, err)
+	require.Equal(t, publishOutcomeCreated, results[0].Outcome)
+
+	board := fake.Board(keyPair.PublicKey)
+	require.NotNil(t, board)
+	require.Contains(t, string(board.Content), "some content")
+	require.Equal(t, entry.Published, board.Timestamp)
+
+	// Publishing an older entry is refused before it gets to the server.
+	olderEntry := *entry
+	olderEntry.Published = entry.Published.Add(-1 * time.Hour)
+	results, err = updateSpring(ctx, newHTTPRequester(), keys, []string{server.URL}, mustRenderBoard(t, &olderEntry), nil, false, 1, time.Now)
+	require.NoError(t, err)
+	require.Equal(t, publishOutcomeConflict, results[0].Outcome)
+
+	// Publishing the same board again is a conflict on the server's end since
+	// its timestamp isn't newer.
+	results, err = updateSpring(ctx, newHTTPRequester(), keys, []string{server.URL}, mustRenderBoard(t, entry), nil, false, 1, time.Now)
+	require.NoError(t, err)
+	require.Equal(t, publishOutcomeConflict, results[0].Outcome)
+
+	// A board in the future is rejected.
+	futureEntry := *entry
+	futureEntry.Published = entry.Published.Add(48 * time.Hour)
+	results, err = updateSpring(ctx, newHTTPRequester(), keys, []string{server.URL}, mustRenderBoard(t, &futureEntry), nil, false, 1, time.Now)
+	require.Error(t, err)
+	require.Equal(t, publishOutcomeFailed, results[0].Outcome)
+}
+
+func TestUpdateSpringMultipleServers(t *testing.T) {
+	ctx := context.Background()
+	keyPair := MustParseKeyPairUnchecked(samplePrivateKey)
+	keys := []*publishKey{{KeyPair: keyPair, Role: keyRolePrimary}}
+
+	newServer := func(putStatus int) *httptest.Server {
+		var board []byte
+		var mu sync.Mutex
+
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch r.Method {
+			case http.MethodGet:
+				if board == nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write(board)
+
+			case http.MethodPut:
+				if putStatus == http.StatusCreated {
+					board, _ = io.ReadAll(r.Body)
+				}
+				w.WriteHeader(putStatus)
+			}
+		}))
+	}
+
+	created := newServer(http.StatusCreated)
+	defer created.Close()
+
+	conflict := newServer(http.StatusConflict)
+	defer conflict.Close()
+
+	failed := newServer(http.StatusBadRequest)
+	defer failed.Close()
+
+	springURLs := []string{created.URL, conflict.URL, failed.URL}
+
+	entry := &Entry{
+		Title:     "a title",
+		Content:   &EntryContent{Content: "<p>some content</p>"},
+		Published: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+	}
+
+	outcomes := func(results []*publishResult) map[string]publishOutcome {
+		outcomes := make(map[string]publishOutcome)
+		for _, result := range results {
+			outcomes[result.SpringURL] = result.Outcome
+		}
+		return outcomes
+	}
+
+	t.Run("QuorumMet", func(t *testing.T) {
+		results, err := updateSpring(ctx, newHTTPRequester(), keys, springURLs, mustRenderBoard(t, entry), nil, false, 2, time.Now)
+		require.NoError(t, err)
+		require.Equal(t, map[string]publishOutcome{
+			created.URL:  publishOutcomeCreated,
+			conflict.URL: publishOutcomeConflict,
+			failed.URL:   publishOutcomeFailed,
+		}, outcomes(results))
+	})
+
+	t.Run("QuorumNotMet", func(t *testing.T) {
+		results, err := updateSpring(ctx, newHTTPRequester(), keys, springURLs, mustRenderBoard(t, entry), nil, false, 3, time.Now)
+		require.ErrorContains(t, err, "accepted by only 2 of 3 server(s), but quorum is 3")
+		require.Len(t, results, 3)
+	})
+}
+
+func TestValidateKey(t *testing.T) {
+	keyPair := MustParseKeyPairUnchecked(samplePrivateKey) // expires end of November 2024
+
+	yearMonthDate := func(year, month int) time.Time {
+		return time.Date(year, time.Month(month), 9, 10, 11, 12, 0, time.UTC)
+	}
+
+	const warningWindow = 30 * 24 * time.Hour
+
+	t.Run("Okay", func(t *testing.T) {
+		require.NoError(t, validateKey(keyPair, yearMonthDate(2023, 11), warningWindow))
+	})
+
+	t.Run("ExpiringSoon", func(t *testing.T) {
+		// Warns, but still valid.
+		require.NoError(t, validateKey(keyPair, yearMonthDate(2024, 11), warningWindow))
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		require.ErrorIs(t, validateKey(keyPair, yearMonthDate(2024, 12), warningWindow), ErrKeyExpired)
+	})
+
+	t.Run("NotYetValid", func(t *testing.T) {
+		require.ErrorIs(t, validateKey(keyPair, yearMonthDate(2022, 10), warningWindow), ErrKeyNotYetValid)
+	})
+
+	t.Run("TestKey", func(t *testing.T) {
+		keyPair := MustParseKeyPairUnchecked(TestPrivateKey)
+		require.ErrorIs(t, validateKey(keyPair, yearMonthDate(2023, 11), warningWindow), ErrKeyTest)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		// Any old key generated without the Spring '83 suffix.
+		keyPair := MustParseKeyPairUnchecked("4cd6d1a0bbc2cbbc7ed6cb9a4a96b4c1c13a0c22e37d54b9b0cf3c8bc9d3d0a6")
+		require.ErrorIs(t, validateKey(keyPair, yearMonthDate(2023, 11), warningWindow), ErrKeyInvalid)
+	})
+}
+
+func TestRequestWithRetries(t *testing.T) {
+	ctx := context.Background()
+
+	// Returns the given status codes in order, then 200s.
+	newServer := func(t *testing.T, header http.Header, statusCodes ...int) (*httptest.Server, *int) {
+		t.Helper()
+
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests <= len(statusCodes) {
+				for key, vals := range header {
+					w.Header()[key] = vals
+				}
+				w.WriteHeader(statusCodes[requests-1])
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		}))
+		t.Cleanup(server.Close)
+
+		return server, &requests
+	}
+
+	t.Run("RetriesThenSucceeds", func(t *testing.T) {
+		requester := newTestRequester(http.DefaultTransport)
+		server, requests := newServer(t, nil, http.StatusBadGateway, http.StatusServiceUnavailable)
+
+		resp, err := requester.requestWithRetries(ctx, http.MethodGet, server.URL, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "ok", string(resp.Body))
+		require.Equal(t, 3, *requests)
+	})
+
+	t.Run("GivesUpAfterMaxAttempts", func(t *testing.T) {
+		requester := newTestRequester(http.DefaultTransport)
+		requester.RetryPolicy.MaxAttempts = 2
+		server, requests := newServer(t, nil, http.StatusInternalServerError, http.StatusInternalServerError)
+
+		_, err := requester.requestWithRetries(ctx, http.MethodGet, server.URL, nil, nil)
+		var statusErr *statusCodeError
+		require.ErrorAs(t, err, &statusErr)
+		require.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+		require.Equal(t, 2, *requests)
+	})
+
+	t.Run("DoesNotRetryClientError", func(t *testing.T) {
+		requester := newTestRequester(http.DefaultTransport)
+		server, requests := newServer(t, nil, http.StatusBadRequest)
+
+		_, err := requester.requestWithRetries(ctx, http.MethodGet, server.URL, nil, nil)
+		require.Error(t, err)
+		require.Equal(t, 1, *requests)
+	})
+
+	t.Run("RetryAfterExceedsMaxTotalWait", func(t *testing.T) {
+		requester := newTestRequester(http.DefaultTransport)
+		server, requests := newServer(t, http.Header{"Retry-After": []string{"60"}}, http.StatusTooManyRequests)
+
+		_, err := requester.requestWithRetries(ctx, http.MethodGet, server.URL, nil, nil)
+		var statusErr *statusCodeError
+		require.ErrorAs(t, err, &statusErr)
+		require.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		require.Equal(t, 1, *requests)
+	})
+
+	t.Run("RetryAfterDateUsesClock", func(t *testing.T) {
+		// An HTTP date a minute past the requester's clock exceeds the max
+		// total wait, even though it's long past by the wall clock.
+		now := time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC)
+		requester := newTestRequester(http.DefaultTransport)
+		requester.Now = func() time.Time { return now }
+		server, requests := newServer(t, http.Header{
+			"Retry-After": []string{now.Add(time.Minute).Format(http.TimeFormat)},
+		}, http.StatusServiceUnavailable)
+
+		_, err := requester.requestWithRetries(ctx, http.MethodGet, server.URL, nil, nil)
+		var statusErr *statusCodeError
+		require.ErrorAs(t, err, &statusErr)
+		require.Equal(t, 1, *requests)
+	})
+
+	t.Run("ContextCancelledDuringWait", func(t *testing.T) {
+		requester := newTestRequester(http.DefaultTransport)
+		requester.RetryPolicy.BaseDelay = time.Hour
+		requester.RetryPolicy.MaxTotalWait = 2 * time.Hour
+		server, requests := newServer(t, nil, http.StatusServiceUnavailable)
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := requester.requestWithRetries(ctx, http.MethodGet, server.URL, nil, nil)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, *requests)
+	})
+
+	t.Run("ClosesBodyEachAttempt", func(t *testing.T) {
+		var bodies []*closeTrackingBody
+		requester := newTestRequester(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			statusCode := http.StatusOK
+			if len(bodies) < 2 {
+				statusCode = http.StatusServiceUnavailable
+			}
+
+			// Every body before this one should've been closed already.
+			for _, body := range bodies {
+				require.True(t, body.closed)
+			}
+
+			body := &closeTrackingBody{Reader: strings.NewReader("ok")}
+			bodies = append(bodies, body)
+			return &http.Response{Body: body, Header: http.Header{}, StatusCode: statusCode}, nil
+		}))
+
+		_, err := requester.requestWithRetries(ctx, http.MethodGet, "https://example.com", nil, nil)
+		require.NoError(t, err)
+		require.Len(t, bodies, 3)
+	})
+
+	t.Run("RetriesConnectionRefused", func(t *testing.T) {
+		requester := newTestRequester(http.DefaultTransport)
+		requester.RetryPolicy.MaxAttempts = 2
+
+		// Start and immediately close a server to get an address that
+		// refuses connections.
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		_, err := requester.requestWithRetries(ctx, http.MethodGet, server.URL, nil, nil)
+		require.ErrorIs(t, err, syscall.ECONNREFUSED)
+	})
 }
 
 func TestShouldRetryStatusCode(t *testing.T) {
 	require.True(t, shouldRetryStatusCode(http.StatusTooManyRequests))
 	require.True(t, shouldRetryStatusCode(http.StatusInternalServerError))
+	require.True(t, shouldRetryStatusCode(http.StatusBadGateway))
+	require.True(t, shouldRetryStatusCode(http.StatusServiceUnavailable))
+	require.True(t, shouldRetryStatusCode(http.StatusGatewayTimeout))
+
+	require.False(t, shouldRetryStatusCode(http.StatusBadRequest))
+	require.False(t, shouldRetryStatusCode(http.StatusNotFound))
 
 	// Conflict is returned by a Spring '83 implementation in cases where a
 	// newer version of a board has already been posted, so if we encounter
@@ -32,6 +827,9 @@ func TestShouldRetryStatusCode(t *testing.T) {
 	require.False(t, shouldRetryStatusCode(http.StatusConflict))
 }
 
+// Base URL of sampleContent, which contains root-relative links.
+var sampleBaseURL = mustParseURL("https://brandur.org/")
+
 //nolint:lll
 const sampleContent = `<p>You&rsquo;ll have to give me a break on photo quality for this one &ndash; it&rsquo;s hard getting something good through the foggy glass of a plane window.</p>
 
@@ -65,14 +863,11 @@ const sampleContentCanonicalized = `<p>You&rsquo;ll have to give me a break on p
 <p>Last weekend I wrote a <a href="https://github.com/brandur/spring83-keygen">Spring &lsquo;83 key generator</a>, and on the flight got maybe halfway to a working server implementation. Tomorrow, more Seattle, more Spring &lsquo;83, and work time spent on SSO and polish on a forthcoming metrics product for Bridge.</p>
 
 
-<img src="https://brandur.org/photographs/sequences/030_large.jpg"
-    srcset="/photographs/sequences/030_large@2x.jpg 2x, /photographs/sequences/030_large.jpg 1x">
+<img src="https://brandur.org/photographs/sequences/030_large.jpg" srcset="https://brandur.org/photographs/sequences/030_large@2x.jpg 2x, https://brandur.org/photographs/sequences/030_large.jpg 1x">
 
-<img src="https://brandur.org/photographs/sequences/030b_large.jpg"
-    srcset="/photographs/sequences/030b_large@2x.jpg 2x, /photographs/sequences/030b_large.jpg 1x">
+<img src="https://brandur.org/photographs/sequences/030b_large.jpg" srcset="https://brandur.org/photographs/sequences/030b_large@2x.jpg 2x, https://brandur.org/photographs/sequences/030b_large.jpg 1x">
 
-<img src="https://brandur.org/photographs/sequences/030c_large.jpg"
-    srcset="/photographs/sequences/030c_large@2x.jpg 2x, /photographs/sequences/030c_large.jpg 1x">`
+<img src="https://brandur.org/photographs/sequences/030c_large.jpg" srcset="https://brandur.org/photographs/sequences/030c_large@2x.jpg 2x, https://brandur.org/photographs/sequences/030c_large.jpg 1x">`
 
 //nolint:lll
 const sampleContentMinimized = `<p>You’ll have to give me a break on photo quality for this one – it’s hard getting something good through the foggy glass of a plane window.</p><p>This is <a href="https://en.wikipedia.org/wiki/Mount_Rainier">Mount Rainier</a>, the tallest mountain in Washington state and the Cascade mountain range, and also one of the most dangerous volcanoes in the world. It’s on the list of <a href="https://en.wikipedia.org/wiki/Decade_Volcanoes">Decade Volcanoes</a> thanks to its history of large, destructive eruptions and near proximity to a dense populzation zone. Wikipedia almost notes that it’s the most topologically prominent peak in the contiguous US, dwarfing everything else around it and having quite a striking effect on the eye.</p><p>I just landed in Seattle. It’s colder than expected. Like colder than it rightfully should be in any west coast city. Luckily, I learned from <a href="/nanoglyphs/033-heroku#new-york">my mistake in New York</a> and came equipped with a variety of cold weather gear this time around. I haven’t had a chance to do much yet besides check into my hotel and head over to the flagship Amazon Go store, which was quite busy, but appeared to be about 5% shoppers, and 95% senior Amazon staff chatting in small circles, lauding each other on their own ingenuity. Still, it was nice seeing a downtown that’d regained some of its lost vibrancy.</p><p>I got a coffee, along with a note saying that Amazon is “working on my receipt”, but nothing since. I suspect it might be a Mechanical Turk who ends up piecing together my bill from video rather than the finely tuned neural nets of a hyper-sophisticated ML cluster, but I might be a cynic. On my way out, someone handed me a free banana from a cart parked next to a geodesic dome.</p><p>Last weekend I wrote a <a href="https://github.com/brandur/spring83-keygen">Spring ‘83 key generator</a>, and on the flight got maybe halfway to a working server implementation. Tomorrow, more Seattle, more Spring ‘83, and work time spent on SSO and polish on a forthcoming metrics product for Bridge.</p><img src="/photographs/sequences/030_large.jpg"><img src="/photographs/sequences/030b_large.jpg"><img src="/photographs/sequences/030c_large.jpg">`
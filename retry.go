@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// retryPolicy configures how requestWithRetries retries failed requests.
+type retryPolicy struct {
+	// Total number of attempts made, including the first.
+	MaxAttempts int
+
+	// Delay before the first retry. It's doubled for each retry after that,
+	// and jittered so that many clients failing at once don't all retry in
+	// lockstep.
+	BaseDelay time.Duration
+
+	// Maximum total time spent waiting between attempts. A retry whose delay
+	// would exceed it is never made, including when a server asks for a long
+	// delay with Retry-After.
+	MaxTotalWait time.Duration
+}
+
+// Retry policy used unless configured otherwise.
+var defaultRetryPolicy = retryPolicy{
+	MaxAttempts:  3,
+	BaseDelay:    2 * time.Second,
+	MaxTotalWait: 30 * time.Second,
+}
+
+// Gets the delay before the given retry, where 1 is the first retry. If the
+// server sent a Retry-After, it's honored as a minimum.
+func (p *retryPolicy) Delay(retryNum int, retryAfter time.Duration) time.Duration {
+	backoff := p.BaseDelay << (retryNum - 1)
+
+	// "Equal jitter": half the backoff is fixed and half is random, which
+	// spreads retries out while still backing off meaningfully.
+	delay := backoff / 2
+	if half := int64(backoff / 2); half > 0 {
+		delay += time.Duration(rand.Int63n(half)) //nolint:gosec
+	}
+
+	if retryAfter > delay {
+		return retryAfter
+	}
+
+	return delay
+}
+
+// Parses a Retry-After header, which is either a number of seconds or an HTTP
+// date, into a delay relative to now. The second return value is false if the
+// header is absent or malformed.
+func parseRetryAfter(val string, now time.Time) (time.Duration, bool) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(val); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(val); err == nil {
+		if delay := t.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// Whether an error from making a request is likely to be transient, like a
+// timeout or a dropped connection, and therefore worth retrying. Errors that
+// will happen again no matter how many times a request is made, like a host
+// that doesn't resolve or a bad certificate, are not.
+func isTransientNetworkError(err error) bool {
+	var dnsErr *net.DNSError
+	if xerrors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var netErr net.Error
+	if xerrors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return xerrors.Is(err, io.EOF) ||
+		xerrors.Is(err, io.ErrUnexpectedEOF) ||
+		xerrors.Is(err, syscall.ECONNREFUSED) ||
+		xerrors.Is(err, syscall.ECONNRESET) ||
+		xerrors.Is(err, syscall.EPIPE)
+}
+
+// Sleeps for the given duration, or until the context is cancelled, in which
+// case the context's error is returned.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
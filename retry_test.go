@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := &retryPolicy{MaxAttempts: 5, BaseDelay: 2 * time.Second, MaxTotalWait: time.Minute}
+
+	t.Run("Backoff", func(t *testing.T) {
+		for retryNum, backoff := range map[int]time.Duration{
+			1: 2 * time.Second,
+			2: 4 * time.Second,
+			3: 8 * time.Second,
+		} {
+			for i := 0; i < 100; i++ {
+				delay := policy.Delay(retryNum, 0)
+				require.GreaterOrEqual(t, delay, backoff/2)
+				require.Less(t, delay, backoff)
+			}
+		}
+	})
+
+	t.Run("RetryAfter", func(t *testing.T) {
+		require.Equal(t, 30*time.Second, policy.Delay(1, 30*time.Second))
+	})
+
+	t.Run("RetryAfterShorterThanBackoff", func(t *testing.T) {
+		require.GreaterOrEqual(t, policy.Delay(1, time.Millisecond), time.Second)
+	})
+
+	t.Run("ZeroBaseDelay", func(t *testing.T) {
+		require.Equal(t, time.Duration(0), (&retryPolicy{MaxAttempts: 3}).Delay(1, 0))
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name  string
+		val   string
+		delay time.Duration
+		ok    bool
+	}{
+		{"Empty", "", 0, false},
+		{"Seconds", "120", 2 * time.Minute, true},
+		{"SecondsNegative", "-1", 0, false},
+		{"Date", "Wed, 09 Nov 2022 10:12:12 GMT", time.Minute, true},
+		{"DatePast", "Wed, 09 Nov 2022 10:10:12 GMT", 0, true},
+		{"Malformed", "soon", 0, false},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := parseRetryAfter(tt.val, now)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.delay, delay)
+		})
+	}
+}
+
+func TestIsTransientNetworkError(t *testing.T) {
+	opErr := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: err}
+	}
+
+	require.True(t, isTransientNetworkError(opErr(syscall.ECONNREFUSED)))
+	require.True(t, isTransientNetworkError(opErr(syscall.ECONNRESET)))
+	require.True(t, isTransientNetworkError(xerrors.Errorf("wrapped: %w", io.ErrUnexpectedEOF)))
+	require.True(t, isTransientNetworkError(&net.DNSError{Err: "timeout", IsTimeout: true}))
+	require.True(t, isTransientNetworkError(context.DeadlineExceeded))
+
+	require.False(t, isTransientNetworkError(&net.DNSError{Err: "no such host", IsNotFound: true}))
+	require.False(t, isTransientNetworkError(http.ErrUseLastResponse))
+	require.False(t, isTransientNetworkError(xerrors.New("unsupported protocol scheme")))
+}
+
+func TestSleepContext(t *testing.T) {
+	require.NoError(t, sleepContext(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, sleepContext(ctx, time.Hour), context.Canceled)
+}
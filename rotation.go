@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// keyRole is the role that a key plays in publishing.
+type keyRole string
+
+const (
+	// keyRolePrimary keys get a board rendered from the latest entry.
+	keyRolePrimary keyRole = "primary"
+
+	// keyRoleRedirect keys get a board pointing to another key's board. This is
+	// used while rotating keys so that readers of an old key can find the new
+	// one.
+	keyRoleRedirect keyRole = "redirect"
+)
+
+// publishKey is a keypair to publish a board to along with the role that
+// determines what that board is.
+type publishKey struct {
+	KeyPair *KeyPair
+	Role    keyRole
+
+	// RedirectTo is the public key whose board a redirect board points to.
+	// Only set for keyRoleRedirect.
+	RedirectTo string
+}
+
+// Parses redirect keys from configuration, which is a comma-separated list
+// of hex-encoded private keys, each optionally followed by a colon and the
+// public key that it should redirect to. Keys without an explicit target
+// redirect to the primary key.
+//
+//	<private_key>[:<redirect_to_public_key>],...
+func parseRedirectKeys(s string, primary *KeyPair) ([]*publishKey, error) {
+	var keys []*publishKey
+
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		privateKey, redirectTo, _ := strings.Cut(spec, ":")
+		if redirectTo == "" {
+			redirectTo = primary.PublicKey
+		}
+
+		keyPair, err := ParseKeyPairUnchecked(privateKey)
+		if err != nil {
+			return nil, xerrors.Errorf("error parsing redirect key: %w", err)
+		}
+
+		if _, err := parseKeyUnchecked(redirectTo); err != nil {
+			return nil, xerrors.Errorf("error parsing redirect target for key %s: %w", keyPair.PublicKey, err)
+		}
+
+		if keyPair.PublicKey == redirectTo {
+			return nil, xerrors.Errorf("key %s can't redirect to itself", keyPair.PublicKey)
+		}
+
+		keys = append(keys, &publishKey{KeyPair: keyPair, Role: keyRoleRedirect, RedirectTo: redirectTo})
+	}
+
+	return keys, nil
+}
+
+// Renders a board for a redirect key that points to the board of another key
+// on the same server. See redirectBoardTimestamp for its timestamp.
+func renderRedirectBoard(springURL, redirectTo string, timestamp time.Time) (string, error) {
+	rendered, err := renderLayout("moved.tmpl.html", "This board has moved", springURL+"/"+redirectTo, timestamp)
+	if err != nil {
+		return "", err
+	}
+
+	return minimizeContent(rendered), nil
+}
+
+// Picks the timestamp for a redirect board given the board live on the server
+// for the redirect key, which may be nil if there isn't one.
+//
+// The redirect board has to be strictly newer than the live board or the
+// server will reject it, and on first rotation the live board is usually the
+// old key's content board with the same timestamp as the new one's. On the
+// other hand, a redirect board that's already live should keep its timestamp
+// so that it's identical from run to run rather than replaced on every one.
+// So:
+//
+//   - If the live board is already this redirect board, its timestamp is kept.
+//   - If there's no live board of ours, or contentTimestamp (that of the board
+//     being redirected to) is newer than it, contentTimestamp is used.
+//   - Otherwise, now is used, or a second past the live board if that's not
+//     newer.
+func redirectBoardTimestamp(live *Board, springURL, redirectTo string, contentTimestamp, now time.Time) (time.Time, error) {
+	if live == nil || !live.Verified || live.Timestamp.IsZero() {
+		return contentTimestamp, nil
+	}
+
+	liveRedirect, err := renderRedirectBoard(springURL, redirectTo, live.Timestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch {
+	case string(live.Content) == liveRedirect:
+		return live.Timestamp, nil
+	case contentTimestamp.After(live.Timestamp):
+		return contentTimestamp, nil
+	}
+
+	timestamp := now.UTC().Truncate(time.Second)
+	if !timestamp.After(live.Timestamp) {
+		timestamp = live.Timestamp.Add(time.Second)
+	}
+	return timestamp, nil
+}
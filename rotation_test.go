@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRedirectKeys(t *testing.T) {
+	primary := MustParseKeyPairUnchecked(samplePrivateKey)
+	oldKeyPair := mustGenerateKeyPair(t)
+	otherKeyPair := mustGenerateKeyPair(t)
+
+	t.Run("Empty", func(t *testing.T) {
+		keys, err := parseRedirectKeys("", primary)
+		require.NoError(t, err)
+		require.Empty(t, keys)
+	})
+
+	t.Run("DefaultsToPrimary", func(t *testing.T) {
+		keys, err := parseRedirectKeys(oldKeyPair.PrivateKey, primary)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.Equal(t, oldKeyPair.PublicKey, keys[0].KeyPair.PublicKey)
+		require.Equal(t, keyRoleRedirect, keys[0].Role)
+		require.Equal(t, primary.PublicKey, keys[0].RedirectTo)
+	})
+
+	t.Run("Multiple", func(t *testing.T) {
+		keys, err := parseRedirectKeys(
+			oldKeyPair.PrivateKey+", "+otherKeyPair.PrivateKey+":"+oldKeyPair.PublicKey,
+			primary,
+		)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		require.Equal(t, primary.PublicKey, keys[0].RedirectTo)
+		require.Equal(t, otherKeyPair.PublicKey, keys[1].KeyPair.PublicKey)
+		require.Equal(t, oldKeyPair.PublicKey, keys[1].RedirectTo)
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		_, err := parseRedirectKeys("not-a-key", primary)
+		require.ErrorContains(t, err, "error parsing redirect key")
+	})
+
+	t.Run("InvalidTarget", func(t *testing.T) {
+		_, err := parseRedirectKeys(oldKeyPair.PrivateKey+":not-a-key", primary)
+		require.ErrorContains(t, err, "error parsing redirect target")
+	})
+
+	t.Run("Self", func(t *testing.T) {
+		_, err := parseRedirectKeys(primary.PrivateKey, primary)
+		require.ErrorContains(t, err, "can't redirect to itself")
+	})
+}
+
+func TestRenderRedirectBoard(t *testing.T) {
+	timestamp := time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC)
+
+	rendered, err := renderRedirectBoard("https://spring.example.com", samplePublicKey, timestamp)
+	require.NoError(t, err)
+	require.Contains(t, rendered, `<time datetime="2022-11-09T10:11:12Z">`)
+	require.Contains(t, rendered, `href="https://spring.example.com/`+samplePublicKey+`"`)
+	require.LessOrEqual(t, len(rendered), maxBoardSize)
+}
+
+func TestUpdateSpringWithRedirect(t *testing.T) {
+	ctx := context.Background()
+	primary := MustParseKeyPairUnchecked(samplePrivateKey)
+	oldKeyPair := mustGenerateKeyPair(t)
+
+	var (
+		boards = map[string][]byte{}
+		mu     sync.Mutex
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		publicKey := strings.TrimPrefix(r.URL.Path, "/")
+
+		switch r.Method {
+		case http.MethodGet:
+			board, ok := boards[publicKey]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(board)
+
+		case http.MethodPut:
+			boards[publicKey], _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	entry := &Entry{
+		Title:     "a title",
+		Content:   &EntryContent{Content: "<p>some content</p>"},
+		Published: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+	}
+
+	keys := []*publishKey{
+		{KeyPair: primary, Role: keyRolePrimary},
+		{KeyPair: oldKeyPair, Role: keyRoleRedirect, RedirectTo: primary.PublicKey},
+	}
+
+	_, err := updateSpring(ctx, newHTTPRequester(), keys, []string{server.URL}, mustRenderBoard(t, entry), nil, false, 1, time.Now)
+	require.NoError(t, err)
+
+	require.Contains(t, string(boards[primary.PublicKey]), "some content")
+	require.Contains(t, string(boards[oldKeyPair.PublicKey]), server.URL+"/"+primary.PublicKey)
+	require.NotContains(t, string(boards[oldKeyPair.PublicKey]), "some content")
+}
+
+func TestUpdateSpringWithRedirectReplacingContent(t *testing.T) {
+	ctx := context.Background()
+	primary := MustParseKeyPairUnchecked(samplePrivateKey)
+	oldKeyPair := mustGenerateKeyPair(t)
+	now := time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC)
+
+	// Like a real server, rejects boards that aren't newer than the one it
+	// has.
+	type storedBoard struct {
+		content   []byte
+		signature string
+		timestamp time.Time
+	}
+	var (
+		boards = map[string]*storedBoard{}
+		mu     sync.Mutex
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		publicKey := strings.TrimPrefix(r.URL.Path, "/")
+
+		switch r.Method {
+		case http.MethodGet:
+			board, ok := boards[publicKey]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Spring-Signature", board.signature)
+			_, _ = w.Write(board.content)
+
+		case http.MethodPut:
+			content, _ := io.ReadAll(r.Body)
+			timestamp, err := parseBoardTimestamp(content)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			if existing, ok := boards[publicKey]; ok && !timestamp.After(existing.timestamp) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+
+			boards[publicKey] = &storedBoard{content, r.Header.Get("Spring-Signature"), timestamp}
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	board := mustRenderBoard(t, &Entry{
+		Title:     "a title",
+		Content:   &EntryContent{Content: "<p>some content</p>"},
+		Published: time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC),
+	})
+
+	publish := func(keys ...*publishKey) map[string]publishOutcome {
+		t.Helper()
+
+		results, err := updateSpring(ctx, newHTTPRequester(), keys, []string{server.URL}, board, nil, false, 1,
+			func() time.Time { return now })
+		require.NoError(t, err)
+
+		outcomes := make(map[string]publishOutcome)
+		for _, result := range results {
+			outcomes[result.PublicKey] = result.Outcome
+		}
+		return outcomes
+	}
+
+	// Before rotation, the old key has the content board.
+	publish(&publishKey{KeyPair: oldKeyPair, Role: keyRolePrimary})
+	require.Contains(t, string(boards[oldKeyPair.PublicKey].content), "some content")
+
+	// After rotation, the same content goes to the new key, and the old key's
+	// board is replaced by a redirect newer than the content board it had.
+	keys := []*publishKey{
+		{KeyPair: primary, Role: keyRolePrimary},
+		{KeyPair: oldKeyPair, Role: keyRoleRedirect, RedirectTo: primary.PublicKey},
+	}
+	outcomes := publish(keys...)
+	require.Equal(t, publishOutcomeCreated, outcomes[primary.PublicKey])
+	require.Equal(t, publishOutcomeCreated, outcomes[oldKeyPair.PublicKey])
+	require.Contains(t, string(boards[oldKeyPair.PublicKey].content), server.URL+"/"+primary.PublicKey)
+	require.Equal(t, now, boards[oldKeyPair.PublicKey].timestamp)
+
+	// On later runs, the redirect board keeps its timestamp.
+	now = now.Add(24 * time.Hour)
+	outcomes = publish(keys...)
+	require.Equal(t, publishOutcomeConflict, outcomes[oldKeyPair.PublicKey])
+	require.Equal(t, now.Add(-24*time.Hour), boards[oldKeyPair.PublicKey].timestamp)
+}
+
+func TestRedirectBoardTimestamp(t *testing.T) {
+	contentTimestamp := time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC)
+	now := time.Date(2022, 11, 20, 0, 0, 0, 0, time.UTC)
+
+	redirect, err := renderRedirectBoard("https://example.com", samplePublicKey, contentTimestamp.Add(-time.Hour))
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name     string
+		live     *Board
+		expected time.Time
+	}{
+		{"NoLiveBoard", nil, contentTimestamp},
+		{"Unverified", &Board{Content: []byte("x"), Timestamp: contentTimestamp.Add(time.Hour)}, contentTimestamp},
+		{"OlderLiveBoard", &Board{Content: []byte("x"), Timestamp: contentTimestamp.Add(-time.Hour), Verified: true}, contentTimestamp},
+		{"SameTimestamp", &Board{Content: []byte("x"), Timestamp: contentTimestamp, Verified: true}, now},
+		{"LiveBoardAfterNow", &Board{Content: []byte("x"), Timestamp: now, Verified: true}, now.Add(time.Second)},
+		{"AlreadyRedirect", &Board{Content: []byte(redirect), Timestamp: contentTimestamp.Add(-time.Hour), Verified: true}, contentTimestamp.Add(-time.Hour)},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, err := redirectBoardTimestamp(tt.live, "https://example.com", samplePublicKey, contentTimestamp, now)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, timestamp)
+		})
+	}
+}
+
+func mustGenerateKeyPair(t *testing.T) *KeyPair {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return KeyPairFromRaw(privateKey)
+}
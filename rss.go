@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// RSS represents an RSS 2.0 feed. Only the subset of it that's needed to
+// produce entries is modeled.
+type RSS struct {
+	XMLName struct{}    `xml:"rss"`
+	Channel *RSSChannel `xml:"channel"`
+}
+
+// RSSChannel is the channel of an RSS 2.0 feed, which contains its items.
+type RSSChannel struct {
+	Title string     `xml:"title"`
+	Links []*RSSLink `xml:"link"`
+	Items []*RSSItem `xml:"item"`
+}
+
+// RSSLink is a `<link>` element. Most feeds also include an `<atom:link
+// rel="self">`, which has the same name and would otherwise be mistaken for
+// the RSS one, so the namespace is kept to tell them apart.
+type RSSLink struct {
+	XMLName xml.Name
+	Href    string `xml:",chardata"`
+}
+
+// RSSItem is a single item in an RSS 2.0 feed.
+type RSSItem struct {
+	Title       string     `xml:"title"`
+	Links       []*RSSLink `xml:"link"`
+	Description string     `xml:"description"`
+	GUID        string     `xml:"guid"`
+	PubDate     string     `xml:"pubDate"`
+	Author      string     `xml:"author"`
+	Categories  []string   `xml:"category"`
+
+	// From the widely used content and Dublin Core modules, which are how
+	// most feeds provide full content and author names respectively.
+	ContentEncoded string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creator        string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+// Layouts for an RSS `pubDate`. The spec calls for RFC 822, but in practice
+// feeds use RFC 1123 with either a numeric or named zone, and sometimes omit
+// the day of the week or pad the day inconsistently.
+var rssDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// ToFeed converts an RSS feed into the Atom-based Feed used everywhere else
+// so that it can go through the same pipeline.
+func (r *RSS) ToFeed() (*Feed, error) {
+	if r.Channel == nil {
+		return nil, xerrors.Errorf("RSS feed has no channel")
+	}
+
+	feed := &Feed{Title: r.Channel.Title}
+
+	if link := rssLink(r.Channel.Links); link != "" {
+		feed.Links = []*Link{{Rel: "alternate", Href: link}}
+	}
+
+	for _, item := range r.Channel.Items {
+		// One bad item shouldn't take down the whole feed.
+		entry, err := item.ToEntry()
+		if err != nil {
+			logger.Warnf("Skipping RSS item %q: %v", item.Title, err)
+			continue
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed, nil
+}
+
+// ToEntry converts an RSS item to an Atom entry. Content comes from
+// `content:encoded` if present, in which case `description` is treated as a
+// summary, and otherwise from `description`.
+func (i *RSSItem) ToEntry() (*Entry, error) {
+	entry := &Entry{
+		Title:      i.Title,
+		ID:         i.GUID,
+		AuthorName: i.Creator,
+	}
+
+	if i.ContentEncoded != "" {
+		entry.Content = &EntryContent{Content: i.ContentEncoded, Type: "html"}
+		entry.Summary = i.Description
+	} else if i.Description != "" {
+		entry.Content = &EntryContent{Content: i.Description, Type: "html"}
+	}
+
+	link := rssLink(i.Links)
+	if link != "" {
+		entry.Link = &Link{Rel: "alternate", Href: link}
+	}
+
+	// A GUID is optional, and the link is the next best unique identifier.
+	if entry.ID == "" {
+		entry.ID = link
+	}
+
+	if entry.AuthorName == "" {
+		entry.AuthorName = i.Author
+	}
+
+	for _, category := range i.Categories {
+		entry.Categories = append(entry.Categories, &Category{Term: category})
+	}
+
+	if i.PubDate != "" {
+		published, err := parseRSSDate(i.PubDate)
+		if err != nil {
+			return nil, err
+		}
+
+		// RSS has no separate concept of an update time.
+		entry.Published = published
+		entry.Updated = published
+	}
+
+	return entry, nil
+}
+
+// Gets the URL of the RSS `<link>` among links, ignoring those from other
+// namespaces like Atom's.
+func rssLink(links []*RSSLink) string {
+	for _, link := range links {
+		if link.XMLName.Space == "" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+func parseRSSDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	for _, layout := range rssDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, xerrors.Errorf("error parsing RSS date %q", s)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const sampleRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:atom="http://www.w3.org/2005/Atom"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+	<title>Sequences</title>
+	<link>https://example.com/sequences</link>
+	<atom:link href="https://example.com/sequences.xml" rel="self" type="application/rss+xml"/>
+	<item>
+		<title>Mount Rainier</title>
+		<atom:link href="https://example.com/sequences/030.xml" rel="alternate"/>
+		<link>https://example.com/sequences/030</link>
+		<guid isPermaLink="false">tag:example.com,2022:sequences/030</guid>
+		<pubDate>Wed, 09 Nov 2022 10:11:12 +0000</pubDate>
+		<description>A short summary.</description>
+		<content:encoded><![CDATA[<p>Full content.</p>]]></content:encoded>
+		<dc:creator>Brandur</dc:creator>
+		<category>travel</category>
+		<category>spring</category>
+	</item>
+	<item>
+		<title>No GUID</title>
+		<link>https://example.com/sequences/029</link>
+		<pubDate>Tue, 8 Nov 2022 10:11:12 GMT</pubDate>
+		<description><![CDATA[<p>Description as content.</p>]]></description>
+	</item>
+	<item>
+		<title>Bad Date</title>
+		<link>https://example.com/sequences/028</link>
+		<pubDate>sometime last week</pubDate>
+	</item>
+</channel>
+</rss>`
+
+func TestRSSToFeed(t *testing.T) {
+	var rss RSS
+	require.NoError(t, xml.Unmarshal([]byte(sampleRSS), &rss))
+
+	feed, err := rss.ToFeed()
+	require.NoError(t, err)
+	require.Equal(t, "Sequences", feed.Title)
+	require.Equal(t, []*Link{{Rel: "alternate", Href: "https://example.com/sequences"}}, feed.Links)
+
+	// The item with an unparseable date is skipped.
+	require.Len(t, feed.Entries, 2)
+
+	{
+		entry := feed.Entries[0]
+		require.Equal(t, "Mount Rainier", entry.Title)
+		require.Equal(t, "tag:example.com,2022:sequences/030", entry.ID)
+		require.Equal(t, "https://example.com/sequences/030", entry.Link.Href)
+		require.Equal(t, "<p>Full content.</p>", entry.Content.Content)
+		require.Equal(t, "A short summary.", entry.Summary)
+		require.Equal(t, "Brandur", entry.AuthorName)
+		require.Equal(t, []*Category{{Term: "travel"}, {Term: "spring"}}, entry.Categories)
+		require.Equal(t, time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC), entry.Published)
+		require.Equal(t, entry.Published, entry.Updated)
+	}
+
+	{
+		entry := feed.Entries[1]
+		require.Equal(t, "https://example.com/sequences/029", entry.ID)
+		require.Equal(t, "<p>Description as content.</p>", entry.Content.Content)
+		require.Equal(t, "", entry.Summary)
+		require.Equal(t, time.Date(2022, 11, 8, 10, 11, 12, 0, time.UTC), entry.Published)
+	}
+}
+
+func TestRSSToEntryBadDate(t *testing.T) {
+	_, err := (&RSSItem{Title: "Bad Date", PubDate: "sometime last week"}).ToEntry()
+	require.EqualError(t, err, `error parsing RSS date "sometime last week"`)
+}
+
+func TestParseRSSDate(t *testing.T) {
+	expected := time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC)
+
+	for _, s := range []string{
+		"Wed, 09 Nov 2022 10:11:12 +0000",
+		"Wed, 09 Nov 2022 10:11:12 UTC",
+		"Wed, 9 Nov 2022 10:11:12 GMT",
+		"Wed, 09 Nov 2022 02:11:12 -0800",
+		"9 Nov 2022 10:11:12 +0000",
+	} {
+		actual, err := parseRSSDate(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, actual, s)
+	}
+
+	_, err := parseRSSDate("2022-11-09")
+	require.Error(t, err)
+}
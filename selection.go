@@ -0,0 +1,285 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// entrySelection is a strategy for selecting which entry to publish.
+type entrySelection string
+
+const (
+	// entrySelectionCategoryPriority selects the entry with the highest
+	// priority category, with priorities from configuration. Ties go to the
+	// newest entry.
+	entrySelectionCategoryPriority entrySelection = "category_priority"
+
+	// entrySelectionNewestPublished selects the most recently published
+	// entry. This is the default.
+	entrySelectionNewestPublished entrySelection = "newest_published"
+
+	// entrySelectionNewestUpdated selects the most recently updated entry.
+	entrySelectionNewestUpdated entrySelection = "newest_updated"
+
+	// entrySelectionPinned selects the entry with a configured ID.
+	entrySelectionPinned entrySelection = "pinned"
+
+	// entrySelectionRandom selects a random entry from the newest few.
+	entrySelectionRandom entrySelection = "random"
+
+	// entrySelectionRoundRobin selects the next of the newest few entries on
+	// each run, starting over after the oldest. Requires a cache to remember
+	// where it left off.
+	entrySelectionRoundRobin entrySelection = "round_robin"
+)
+
+var allEntrySelections = []entrySelection{
+	entrySelectionCategoryPriority,
+	entrySelectionNewestPublished,
+	entrySelectionNewestUpdated,
+	entrySelectionPinned,
+	entrySelectionRandom,
+	entrySelectionRoundRobin,
+}
+
+// UnmarshalText parses a strategy from configuration, rejecting unknown ones.
+func (s *entrySelection) UnmarshalText(text []byte) error {
+	for _, selection := range allEntrySelections {
+		if string(text) == string(selection) {
+			*s = selection
+			return nil
+		}
+	}
+
+	names := make([]string, len(allEntrySelections))
+	for i, selection := range allEntrySelections {
+		names[i] = string(selection)
+	}
+
+	return xerrors.Errorf("unknown entry selection %q (should be one of: %s)", string(text), strings.Join(names, ", "))
+}
+
+// entrySelector selects the entry to publish from those in all feeds.
+type entrySelector struct {
+	Name entrySelection
+
+	// Rotates indicates that the selected entry may change from run to run
+	// even when no feeds have, so a run shouldn't be skipped just because
+	// they're unchanged.
+	Rotates bool
+
+	// Select selects an entry from entries, which are non-empty and sorted
+	// newest first by publish time.
+	Select func(entries []*Entry) (*Entry, error)
+
+	// Timestamp gets the timestamp for a board of the selected entry. Servers
+	// only accept a board newer than the one they have, so strategies that
+	// might select an entry older than one published before use the time of
+	// the run instead of the entry's.
+	Timestamp func(entry *Entry, now time.Time) time.Time
+}
+
+// entrySelectorConfig is configuration for building an entrySelector.
+type entrySelectorConfig struct {
+	// CategoryPriorities maps category terms to priorities for
+	// entrySelectionCategoryPriority.
+	CategoryPriorities map[string]int
+
+	// FeedPriorities indicates that some feeds are configured with a
+	// priority, which counts towards entrySelectionCategoryPriority.
+	FeedPriorities bool
+
+	// PinnedID is the ID of the entry for entrySelectionPinned.
+	PinnedID string
+
+	// Window is the number of newest entries to select from for
+	// entrySelectionRandom and entrySelectionRoundRobin.
+	Window int
+}
+
+// Builds the selector for a strategy. cache may be nil, except for
+// entrySelectionRoundRobin.
+func newEntrySelector(name entrySelection, config *entrySelectorConfig, cache *cache) (*entrySelector, error) {
+	publishedTimestamp := func(entry *Entry, now time.Time) time.Time { return entry.Published }
+	nowTimestamp := func(entry *Entry, now time.Time) time.Time { return now.UTC().Truncate(time.Second) }
+
+	switch name {
+	case entrySelectionCategoryPriority:
+		if len(config.CategoryPriorities) < 1 && !config.FeedPriorities {
+			return nil, xerrors.Errorf("entry selection %s needs category or feed priorities", name)
+		}
+
+		return &entrySelector{
+			Name: name,
+			Select: func(entries []*Entry) (*Entry, error) {
+				return selectByCategoryPriority(entries, config.CategoryPriorities), nil
+			},
+			Timestamp: nowTimestamp,
+		}, nil
+
+	case entrySelectionNewestPublished, "":
+		return &entrySelector{
+			Name:      entrySelectionNewestPublished,
+			Select:    func(entries []*Entry) (*Entry, error) { return entries[0], nil },
+			Timestamp: publishedTimestamp,
+		}, nil
+
+	case entrySelectionNewestUpdated:
+		return &entrySelector{
+			Name:      name,
+			Select:    func(entries []*Entry) (*Entry, error) { return selectNewestUpdated(entries), nil },
+			Timestamp: func(entry *Entry, now time.Time) time.Time { return entryUpdated(entry) },
+		}, nil
+
+	case entrySelectionPinned:
+		if config.PinnedID == "" {
+			return nil, xerrors.Errorf("entry selection %s needs an entry ID", name)
+		}
+
+		return &entrySelector{
+			Name: name,
+			Select: func(entries []*Entry) (*Entry, error) {
+				for _, entry := range entries {
+					if entry.ID == config.PinnedID {
+						return entry, nil
+					}
+				}
+				return nil, xerrors.Errorf("pinned entry %q not found in any feed", config.PinnedID)
+			},
+			Timestamp: nowTimestamp,
+		}, nil
+
+	case entrySelectionRandom:
+		if config.Window < 1 {
+			return nil, xerrors.Errorf("entry selection %s needs a window of at least 1, but was %d", name, config.Window)
+		}
+
+		return &entrySelector{
+			Name:    name,
+			Rotates: true,
+			Select: func(entries []*Entry) (*Entry, error) {
+				window := entries[:minInt(config.Window, len(entries))]
+				return window[rand.Intn(len(window))], nil //nolint:gosec
+			},
+			Timestamp: nowTimestamp,
+		}, nil
+
+	case entrySelectionRoundRobin:
+		if config.Window < 1 {
+			return nil, xerrors.Errorf("entry selection %s needs a window of at least 1, but was %d", name, config.Window)
+		}
+
+		if cache == nil {
+			return nil, xerrors.Errorf("entry selection %s needs a cache to remember its position", name)
+		}
+
+		return &entrySelector{
+			Name:    name,
+			Rotates: true,
+			Select: func(entries []*Entry) (*Entry, error) {
+				entry := selectRoundRobin(entries[:minInt(config.Window, len(entries))], cache.GetLastEntryID())
+				cache.SetLastEntryID(entry.ID)
+				return entry, nil
+			},
+			Timestamp: nowTimestamp,
+		}, nil
+	}
+
+	return nil, xerrors.Errorf("unknown entry selection %q", name)
+}
+
+// Parses category priorities from configuration, which is a comma-separated
+// list of category terms and their priorities, like `spring:10,photos:5`.
+func parseCategoryPriorities(s string) (map[string]int, error) {
+	priorities := make(map[string]int)
+
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		term, priorityStr, ok := strings.Cut(spec, ":")
+		if !ok || term == "" {
+			return nil, xerrors.Errorf("category priority %q should be formatted as <term>:<priority>", spec)
+		}
+
+		priority, err := strconv.Atoi(priorityStr)
+		if err != nil {
+			return nil, xerrors.Errorf("error parsing priority of category %q: %w", term, err)
+		}
+
+		priorities[term] = priority
+	}
+
+	return priorities, nil
+}
+
+// Selects the entry with the highest priority, which is that of its highest
+// priority category plus the priority of its feed. Entries without any
+// prioritized category have a category priority of zero. Entries are expected
+// to be sorted newest first so that the newest wins a tie.
+func selectByCategoryPriority(entries []*Entry, priorities map[string]int) *Entry {
+	var best *Entry
+	var bestPriority int
+
+	for _, entry := range entries {
+		var matched bool
+		var priority int
+		for _, category := range entry.Categories {
+			if p, ok := priorities[category.Term]; ok && (!matched || p > priority) {
+				matched, priority = true, p
+			}
+		}
+
+		priority += entry.FeedPriority
+
+		if best == nil || priority > bestPriority {
+			best, bestPriority = entry, priority
+		}
+	}
+
+	return best
+}
+
+// Selects the most recently updated entry.
+func selectNewestUpdated(entries []*Entry) *Entry {
+	best := entries[0]
+	for _, entry := range entries[1:] {
+		if entryUpdated(entry).After(entryUpdated(best)) {
+			best = entry
+		}
+	}
+	return best
+}
+
+// Selects the entry after the one with lastID, or the first if lastID is the
+// last entry or isn't found (like if it's aged out of the window).
+func selectRoundRobin(entries []*Entry, lastID string) *Entry {
+	for i, entry := range entries {
+		if lastID != "" && entry.ID == lastID {
+			return entries[(i+1)%len(entries)]
+		}
+	}
+	return entries[0]
+}
+
+// Gets when an entry was last updated. RSS and some other sources may not
+// have an update time, in which case its publish time is used.
+func entryUpdated(entry *Entry) time.Time {
+	if entry.Updated.IsZero() {
+		return entry.Published
+	}
+	return entry.Updated
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntrySelectionUnmarshalText(t *testing.T) {
+	for _, selection := range allEntrySelections {
+		var parsed entrySelection
+		require.NoError(t, parsed.UnmarshalText([]byte(selection)))
+		require.Equal(t, selection, parsed)
+	}
+
+	var parsed entrySelection
+	require.ErrorContains(t, parsed.UnmarshalText([]byte("oldest")), `unknown entry selection "oldest"`)
+}
+
+func TestNewEntrySelector(t *testing.T) {
+	now := time.Date(2022, 11, 20, 1, 2, 3, 456, time.UTC)
+
+	newEntry := func(id string, published, updated time.Time, categories ...string) *Entry {
+		entry := &Entry{ID: id, Published: published, Updated: updated}
+		for _, term := range categories {
+			entry.Categories = append(entry.Categories, &Category{Term: term})
+		}
+		return entry
+	}
+
+	// Sorted newest first by publish time, as selectors expect.
+	entries := []*Entry{
+		newEntry("c", time.Date(2022, 11, 3, 0, 0, 0, 0, time.UTC), time.Time{}),
+		newEntry("b", time.Date(2022, 11, 2, 0, 0, 0, 0, time.UTC), time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC), "spring"),
+		newEntry("a", time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC), time.Time{}, "photos", "spring"),
+	}
+
+	mustSelector := func(t *testing.T, name entrySelection, config *entrySelectorConfig, cache *cache) *entrySelector {
+		t.Helper()
+
+		selector, err := newEntrySelector(name, config, cache)
+		require.NoError(t, err)
+		return selector
+	}
+
+	mustSelect := func(t *testing.T, selector *entrySelector) *Entry {
+		t.Helper()
+
+		entry, err := selector.Select(entries)
+		require.NoError(t, err)
+		return entry
+	}
+
+	t.Run("NewestPublished", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionNewestPublished, &entrySelectorConfig{}, nil)
+		entry := mustSelect(t, selector)
+		require.Equal(t, "c", entry.ID)
+		require.Equal(t, entry.Published, selector.Timestamp(entry, now))
+		require.False(t, selector.Rotates)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		selector := mustSelector(t, "", &entrySelectorConfig{}, nil)
+		require.Equal(t, entrySelectionNewestPublished, selector.Name)
+	})
+
+	t.Run("NewestUpdated", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionNewestUpdated, &entrySelectorConfig{}, nil)
+		entry := mustSelect(t, selector)
+		require.Equal(t, "b", entry.ID)
+		require.Equal(t, entry.Updated, selector.Timestamp(entry, now))
+	})
+
+	t.Run("Pinned", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionPinned, &entrySelectorConfig{PinnedID: "a"}, nil)
+		entry := mustSelect(t, selector)
+		require.Equal(t, "a", entry.ID)
+		require.Equal(t, time.Date(2022, 11, 20, 1, 2, 3, 0, time.UTC), selector.Timestamp(entry, now))
+	})
+
+	t.Run("PinnedNotFound", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionPinned, &entrySelectorConfig{PinnedID: "z"}, nil)
+		_, err := selector.Select(entries)
+		require.EqualError(t, err, `pinned entry "z" not found in any feed`)
+	})
+
+	t.Run("PinnedMissingID", func(t *testing.T) {
+		_, err := newEntrySelector(entrySelectionPinned, &entrySelectorConfig{}, nil)
+		require.EqualError(t, err, "entry selection pinned needs an entry ID")
+	})
+
+	t.Run("CategoryPriority", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionCategoryPriority, &entrySelectorConfig{
+			CategoryPriorities: map[string]int{"photos": 10, "spring": 5},
+		}, nil)
+		require.Equal(t, "a", mustSelect(t, selector).ID)
+	})
+
+	t.Run("CategoryPriorityTieGoesToNewest", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionCategoryPriority, &entrySelectorConfig{
+			CategoryPriorities: map[string]int{"spring": 5},
+		}, nil)
+		require.Equal(t, "b", mustSelect(t, selector).ID)
+	})
+
+	t.Run("CategoryPriorityNegative", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionCategoryPriority, &entrySelectorConfig{
+			CategoryPriorities: map[string]int{"spring": -1},
+		}, nil)
+		require.Equal(t, "c", mustSelect(t, selector).ID)
+	})
+
+	t.Run("CategoryPriorityMissingPriorities", func(t *testing.T) {
+		_, err := newEntrySelector(entrySelectionCategoryPriority, &entrySelectorConfig{}, nil)
+		require.EqualError(t, err, "entry selection category_priority needs category or feed priorities")
+	})
+
+	t.Run("CategoryPriorityFeedPriority", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionCategoryPriority, &entrySelectorConfig{
+			CategoryPriorities: map[string]int{"photos": 10},
+			FeedPriorities:     true,
+		}, nil)
+
+		// Feed priority is added to category priority.
+		feedEntries := []*Entry{
+			{ID: "x", FeedPriority: 11},
+			{ID: "y", FeedPriority: 0, Categories: []*Category{{Term: "photos"}}},
+		}
+		entry, err := selector.Select(feedEntries)
+		require.NoError(t, err)
+		require.Equal(t, "x", entry.ID)
+
+		feedEntries[1].FeedPriority = 2
+		entry, err = selector.Select(feedEntries)
+		require.NoError(t, err)
+		require.Equal(t, "y", entry.ID)
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		selector := mustSelector(t, entrySelectionRandom, &entrySelectorConfig{Window: 2}, nil)
+		require.True(t, selector.Rotates)
+
+		for i := 0; i < 20; i++ {
+			require.Contains(t, []string{"b", "c"}, mustSelect(t, selector).ID)
+		}
+	})
+
+	t.Run("RoundRobin", func(t *testing.T) {
+		cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"))
+		require.NoError(t, err)
+
+		selector := mustSelector(t, entrySelectionRoundRobin, &entrySelectorConfig{Window: 2}, cache)
+		require.True(t, selector.Rotates)
+
+		require.Equal(t, "c", mustSelect(t, selector).ID)
+		require.Equal(t, "b", mustSelect(t, selector).ID)
+		require.Equal(t, "c", mustSelect(t, selector).ID)
+		require.Equal(t, "c", cache.GetLastEntryID())
+
+		// Starts over if the last entry has aged out of the window.
+		cache.SetLastEntryID("a")
+		require.Equal(t, "c", mustSelect(t, selector).ID)
+	})
+
+	t.Run("RoundRobinWithoutCache", func(t *testing.T) {
+		_, err := newEntrySelector(entrySelectionRoundRobin, &entrySelectorConfig{Window: 2}, nil)
+		require.EqualError(t, err, "entry selection round_robin needs a cache to remember its position")
+	})
+
+	t.Run("WindowTooSmall", func(t *testing.T) {
+		_, err := newEntrySelector(entrySelectionRandom, &entrySelectorConfig{}, nil)
+		require.EqualError(t, err, "entry selection random needs a window of at least 1, but was 0")
+	})
+}
+
+func TestParseCategoryPriorities(t *testing.T) {
+	{
+		priorities, err := parseCategoryPriorities("")
+		require.NoError(t, err)
+		require.Empty(t, priorities)
+	}
+
+	{
+		priorities, err := parseCategoryPriorities("spring:10, photos:-5")
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{"spring": 10, "photos": -5}, priorities)
+	}
+
+	{
+		_, err := parseCategoryPriorities("spring")
+		require.EqualError(t, err, `category priority "spring" should be formatted as <term>:<priority>`)
+	}
+
+	{
+		_, err := parseCategoryPriorities("spring:high")
+		require.ErrorContains(t, err, `error parsing priority of category "spring"`)
+	}
+}
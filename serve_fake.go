@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/brandur/neospring-bridge/internal/springfake"
+)
+
+// Runs the `serve-fake` subcommand, which runs an in-memory fake Spring '83
+// server until interrupted. Point SPRING_URL at it to try publishing locally
+// without touching a real server. Boards are lost when it stops.
+//
+//	neospring-bridge serve-fake [-addr <host:port>]
+func runServeFake(ctx context.Context, args []string, out io.Writer) error {
+	flagSet := flag.NewFlagSet("serve-fake", flag.ContinueOnError)
+	addr := flagSet.String("addr", "127.0.0.1:8083", "address to listen on")
+	if err := flagSet.Parse(args); err != nil {
+		return xerrors.Errorf("error parsing flags: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return xerrors.Errorf("error listening on %q: %w", *addr, err)
+	}
+
+	return serveFake(ctx, listener, out)
+}
+
+// Serves a fake Spring '83 server on the given listener until the context is
+// cancelled.
+func serveFake(ctx context.Context, listener net.Listener, out io.Writer) error {
+	server := &http.Server{
+		Handler:           springfake.NewServer(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Fprintf(out, "Fake Spring '83 server listening on http://%s\n", listener.Addr())
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errChan:
+		return xerrors.Errorf("error serving: %w", err)
+
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return xerrors.Errorf("error shutting down: %w", err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeFake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- serveFake(ctx, listener, &out)
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/" + samplePublicKey)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	cancel()
+	require.NoError(t, <-errChan)
+	require.Contains(t, out.String(), "listening on http://"+listener.Addr().String())
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrBoardNotFound is returned when a Spring '83 server has no board for a
+// key.
+var ErrBoardNotFound = xerrors.New("board not found")
+
+// Board is a board fetched from a Spring '83 server.
+type Board struct {
+	// Content is the board's raw HTML content.
+	Content []byte
+
+	// Signature is the signature from the board's `Spring-Signature` header.
+	Signature []byte
+
+	// Timestamp is the time from the board's `<time datetime="...">` element.
+	// It's the zero time if the board didn't have one.
+	Timestamp time.Time
+
+	// Verified is whether Signature is a valid signature of Content by the
+	// key the board was fetched for. A board that isn't verified shouldn't be
+	// trusted.
+	Verified bool
+}
+
+// From spec: <time datetime="YYYY-MM-DDTHH:MM:SSZ">.
+var boardTimestampRE = regexp.MustCompile(`<time datetime="(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ)">`)
+
+// Fetches the board for the given key from a Spring '83 server, verifying its
+// signature and parsing its timestamp. Returns ErrBoardNotFound if the server
+// has no board for the key.
+//
+// A board whose signature doesn't verify is returned rather than producing an
+// error, but with Verified set to false.
+func fetchBoard(ctx context.Context, requester *httpRequester, springURL string, key *Key) (*Board, error) {
+	resp, err := requester.requestWithRetries(ctx, http.MethodGet, springURL+"/"+key.PublicKey, nil, nil)
+	if err != nil {
+		var statusErr *statusCodeError
+		if xerrors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, ErrBoardNotFound
+		}
+
+		return nil, xerrors.Errorf("error fetching board: %w", err)
+	}
+
+	board := &Board{Content: resp.Body}
+
+	if sigHex := resp.Header.Get("Spring-Signature"); sigHex != "" {
+		board.Signature, err = hex.DecodeString(sigHex)
+		if err != nil {
+			logger.Warnf("Board for key %s has malformed signature: %v", key.PublicKey, err)
+		} else {
+			board.Verified = key.Verify(board.Content, board.Signature)
+		}
+	}
+
+	board.Timestamp, err = parseBoardTimestamp(board.Content)
+	if err != nil {
+		logger.Warnf("Board for key %s has no valid timestamp: %v", key.PublicKey, err)
+	}
+
+	return board, nil
+}
+
+// Parses the timestamp out of a board's `<time datetime="...">` element,
+// which the spec requires to be present.
+func parseBoardTimestamp(content []byte) (time.Time, error) {
+	matches := boardTimestampRE.FindSubmatch(content)
+	if matches == nil {
+		return time.Time{}, xerrors.Errorf("no <time datetime> element found in board")
+	}
+
+	timestamp, err := time.Parse(timestampFormat, string(matches[1]))
+	if err != nil {
+		return time.Time{}, xerrors.Errorf("error parsing board timestamp: %w", err)
+	}
+
+	return timestamp, nil
+}
+
+// Confirms that the board live on a Spring '83 server is the one that was
+// just published, logging a warning if it isn't or if it couldn't be
+// fetched. Neither is an error because the publish itself succeeded, and a
+// server may legitimately be serving a newer board, or a cache in front of it
+// may not have caught up yet.
+func confirmBoard(ctx context.Context, requester *httpRequester, springURL string, key *Key, published []byte) {
+	board, err := fetchBoard(ctx, requester, springURL, key)
+	if err != nil {
+		logger.Warnf("Couldn't confirm published board for key %s on %s: %v", key.PublicKey, springURL, err)
+		return
+	}
+
+	switch {
+	case !board.Verified:
+		logger.Warnf("Live board for key %s failed signature verification", key.PublicKey)
+	case !bytes.Equal(board.Content, published):
+		logger.Warnf("Live board for key %s differs from the one published (live timestamp: %v)",
+			key.PublicKey, board.Timestamp)
+	default:
+		logger.Infof("Confirmed published board is live and verified (timestamp: %v)", board.Timestamp)
+	}
+}
+
+// Runs the `fetch` subcommand, which fetches a board and prints it along with
+// whether its signature verified. The key defaults to SPRING_PUBLIC_KEY and
+// the server to the first one in SPRING_URL.
+//
+// With `-test`, the board for the test key defined by the spec is fetched
+// instead. Servers always return some randomized content for it, so it's a
+// good way of exercising the client against a real server.
+//
+//	neospring-bridge fetch [-url <spring_url>] [-test | <key>]
+func runFetch(ctx context.Context, args []string, out io.Writer) error {
+	flagSet := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	defaultSpringURL, _, _ := strings.Cut(os.Getenv("SPRING_URL"), ",")
+	springURL := flagSet.String("url", defaultSpringURL, "URL of the Spring '83 server (default: first in SPRING_URL)")
+	testKey := flagSet.Bool("test", false, "fetch the board of the Spring '83 test key")
+	if err := flagSet.Parse(args); err != nil {
+		return xerrors.Errorf("error parsing flags: %w", err)
+	}
+
+	publicKey := flagSet.Arg(0)
+	switch {
+	case *testKey && publicKey != "":
+		return xerrors.Errorf("-test can't be combined with a key")
+	case *testKey:
+		publicKey = TestPublicKey
+	case publicKey == "":
+		publicKey = os.Getenv("SPRING_PUBLIC_KEY")
+	}
+
+	switch {
+	case *springURL == "":
+		return xerrors.Errorf("need a server URL from -url or SPRING_URL")
+	case publicKey == "":
+		return xerrors.Errorf("need a key as an argument or from SPRING_PUBLIC_KEY")
+	}
+
+	// Unchecked so that boards for keys that are expired or otherwise
+	// non-compliant (like the test key) can still be inspected.
+	key, err := parseKeyUnchecked(publicKey)
+	if err != nil {
+		return err
+	}
+
+	board, err := fetchBoard(ctx, newHTTPRequester(), *springURL, key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s\n\n", board.Content)
+	fmt.Fprintf(out, "Timestamp: %v\n", board.Timestamp)
+	fmt.Fprintf(out, "Verified:  %v\n", board.Verified)
+
+	return nil
+}
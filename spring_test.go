@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchBoard(t *testing.T) {
+	ctx := context.Background()
+	keyPair := MustParseKeyPairUnchecked(samplePrivateKey)
+
+	const content = `<time datetime="2022-11-09T10:11:12Z"><p>a board</p>`
+
+	newServer := func(t *testing.T, signature string) *httptest.Server {
+		t.Helper()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/"+keyPair.PublicKey {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			w.Header().Set("Spring-Signature", signature)
+			_, _ = w.Write([]byte(content))
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	t.Run("Verified", func(t *testing.T) {
+		server := newServer(t, keyPair.SignHex([]byte(content)))
+
+		board, err := fetchBoard(ctx, newHTTPRequester(), server.URL, &keyPair.Key)
+		require.NoError(t, err)
+		require.Equal(t, []byte(content), board.Content)
+		require.True(t, board.Verified)
+		require.Equal(t, time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC), board.Timestamp)
+	})
+
+	t.Run("BadSignature", func(t *testing.T) {
+		otherKeyPair := MustParseKeyPairUnchecked(TestPrivateKey)
+		server := newServer(t, otherKeyPair.SignHex([]byte(content)))
+
+		board, err := fetchBoard(ctx, newHTTPRequester(), server.URL, &keyPair.Key)
+		require.NoError(t, err)
+		require.False(t, board.Verified)
+	})
+
+	t.Run("MalformedSignature", func(t *testing.T) {
+		server := newServer(t, "not-hex")
+
+		board, err := fetchBoard(ctx, newHTTPRequester(), server.URL, &keyPair.Key)
+		require.NoError(t, err)
+		require.False(t, board.Verified)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		server := newServer(t, "")
+
+		_, err := fetchBoard(ctx, newHTTPRequester(), server.URL, &MustParseKeyPairUnchecked(TestPrivateKey).Key)
+		require.ErrorIs(t, err, ErrBoardNotFound)
+	})
+}
+
+func TestRunFetch(t *testing.T) {
+	ctx := context.Background()
+
+	const content = `<time datetime="2022-11-09T10:11:12Z"><p>a board</p>`
+
+	// Serves a board for any key that it has the private key for.
+	keyPairs := map[string]*KeyPair{}
+	for _, privateKey := range []string{samplePrivateKey, TestPrivateKey} {
+		keyPair := MustParseKeyPairUnchecked(privateKey)
+		keyPairs[keyPair.PublicKey] = keyPair
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyPair, ok := keyPairs[r.URL.Path[1:]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Spring-Signature", keyPair.SignHex([]byte(content)))
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	const expected = content + `
+
+Timestamp: 2022-11-09 10:11:12 +0000 UTC
+Verified:  true
+`
+
+	t.Run("Key", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, runFetch(ctx, []string{"-url", server.URL, samplePublicKey}, &out))
+		require.Equal(t, expected, out.String())
+	})
+
+	t.Run("TestKey", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, runFetch(ctx, []string{"-url", server.URL, "-test"}, &out))
+		require.Equal(t, expected, out.String())
+	})
+
+	t.Run("TestKeyAndKey", func(t *testing.T) {
+		var out bytes.Buffer
+		require.Error(t, runFetch(ctx, []string{"-url", server.URL, "-test", samplePublicKey}, &out))
+	})
+}
+
+func TestParseBoardTimestamp(t *testing.T) {
+	{
+		timestamp, err := parseBoardTimestamp([]byte(`<time datetime="2022-11-09T10:11:12Z"><p>a board</p>`))
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2022, 11, 9, 10, 11, 12, 0, time.UTC), timestamp)
+	}
+
+	{
+		_, err := parseBoardTimestamp([]byte(`<p>a board</p>`))
+		require.Error(t, err)
+	}
+
+	{
+		_, err := parseBoardTimestamp([]byte(`<time datetime="2022-13-09T10:11:12Z">`))
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/xerrors"
+)
+
+// Text of the link appended to truncated content that points back to the
+// original entry.
+const continueReadingText = "Continue reading →"
+
+// Truncates HTML content so that its rendered size fits within the given
+// budget in bytes. Content is parsed into a DOM, and whole elements are kept
+// until one doesn't fit, at which point truncation descends into it to keep as
+// much of it as possible. Because the result is rendered back out from the
+// DOM, it never contains unclosed tags or partially written entities like
+// cutting a string at a byte offset might.
+//
+// If content had to be truncated and continueURL isn't empty, a "continue
+// reading" link to it is appended, with its size counted against the budget.
+// The second return value indicates whether any truncation occurred. If
+// nothing at all could be kept, an empty string is returned.
+func truncateHTML(content string, budget int, continueURL string) (string, bool, error) {
+	container := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+
+	nodes, err := html.ParseFragment(strings.NewReader(content), container)
+	if err != nil {
+		return "", false, xerrors.Errorf("error parsing HTML: %w", err)
+	}
+
+	for _, node := range nodes {
+		container.AppendChild(node)
+	}
+
+	if size, err := renderedChildrenSize(container); err != nil {
+		return "", false, err
+	} else if size <= budget {
+		return content, false, nil
+	}
+
+	var continueReading *html.Node
+	if continueURL != "" {
+		continueReading = continueReadingNode(continueURL)
+
+		size, err := renderedSize(continueReading)
+		if err != nil {
+			return "", false, err
+		}
+
+		budget -= size
+	}
+
+	if budget > 0 {
+		if _, err := truncateChildren(container, budget); err != nil {
+			return "", false, err
+		}
+	} else {
+		removeChildrenFrom(container, container.FirstChild)
+	}
+
+	// A "continue reading" link on its own isn't useful content.
+	if container.FirstChild == nil {
+		return "", true, nil
+	}
+
+	if continueReading != nil {
+		container.AppendChild(continueReading)
+	}
+
+	truncated, err := renderInnerHTML(container)
+	if err != nil {
+		return "", false, err
+	}
+
+	return truncated, true, nil
+}
+
+// Builds a paragraph containing a "continue reading" link to the given URL.
+func continueReadingNode(continueURL string) *html.Node {
+	link := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "a",
+		DataAtom: atom.A,
+		Attr:     []html.Attribute{{Key: "href", Val: continueURL}},
+	}
+	link.AppendChild(&html.Node{Type: html.TextNode, Data: continueReadingText})
+
+	paragraph := &html.Node{Type: html.ElementNode, Data: "p", DataAtom: atom.P}
+	paragraph.AppendChild(link)
+
+	return paragraph
+}
+
+// Removes the given child and all its following siblings from parent.
+func removeChildrenFrom(parent, child *html.Node) {
+	for child != nil {
+		next := child.NextSibling
+		parent.RemoveChild(child)
+		child = next
+	}
+}
+
+// Gets the size of the given node once rendered, including all its children.
+func renderedSize(node *html.Node) (int, error) {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, node); err != nil {
+		return 0, xerrors.Errorf("error rendering HTML: %w", err)
+	}
+	return buf.Len(), nil
+}
+
+// Gets the total rendered size of all of a node's children, but not the node
+// itself.
+func renderedChildrenSize(node *html.Node) (int, error) {
+	var total int
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		size, err := renderedSize(child)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// Truncates the children of the given node in place so that their combined
+// rendered size fits within budget. Returns the size of what was kept.
+func truncateChildren(node *html.Node, budget int) (int, error) {
+	var used int
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		size, err := renderedSize(child)
+		if err != nil {
+			return 0, err
+		}
+
+		if used+size <= budget {
+			used += size
+			continue
+		}
+
+		// This child doesn't fit in its entirety, so everything after it is
+		// dropped, and we try to keep some part of it.
+		removeChildrenFrom(node, child.NextSibling)
+
+		partial, err := truncateNode(child, budget-used)
+		if err != nil {
+			return 0, err
+		}
+
+		if partial == 0 {
+			node.RemoveChild(child)
+		}
+
+		return used + partial, nil
+	}
+
+	return used, nil
+}
+
+// Truncates a single node in place to fit within budget, returning its new
+// rendered size, or zero if nothing of it could be kept, in which case the
+// caller should remove it.
+func truncateNode(node *html.Node, budget int) (int, error) {
+	switch node.Type { //nolint:exhaustive
+	case html.TextNode:
+		node.Data = truncateText(node.Data, budget)
+		if strings.TrimSpace(node.Data) == "" {
+			return 0, nil
+		}
+		return renderedSize(node)
+
+	case html.ElementNode:
+		if node.FirstChild == nil {
+			return 0, nil
+		}
+
+		// Measure the element's tags alone by temporarily detaching its
+		// children.
+		firstChild, lastChild := node.FirstChild, node.LastChild
+		node.FirstChild, node.LastChild = nil, nil
+		shellSize, err := renderedSize(node)
+		node.FirstChild, node.LastChild = firstChild, lastChild
+		if err != nil {
+			return 0, err
+		}
+
+		if shellSize >= budget {
+			return 0, nil
+		}
+
+		childrenSize, err := truncateChildren(node, budget-shellSize)
+		if err != nil {
+			return 0, err
+		}
+
+		if node.FirstChild == nil {
+			return 0, nil
+		}
+
+		return shellSize + childrenSize, nil
+	}
+
+	return 0, nil
+}
+
+// Truncates text on a word boundary so that once escaped and suffixed with an
+// ellipsis it fits within budget. Returns an empty string if not even a single
+// word fits.
+func truncateText(text string, budget int) string {
+	const ellipsis = "…"
+
+	budget -= len(ellipsis)
+
+	var kept string
+	for _, word := range strings.SplitAfter(text, " ") {
+		if len(html.EscapeString(strings.TrimRight(kept+word, " "))) > budget {
+			break
+		}
+		kept += word
+	}
+
+	kept = strings.TrimRight(kept, " ")
+	if strings.TrimSpace(kept) == "" {
+		return ""
+	}
+
+	return kept + ellipsis
+}
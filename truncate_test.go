@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateHTML(t *testing.T) {
+	t.Run("FitsAsIs", func(t *testing.T) {
+		truncated, didTruncate, err := truncateHTML(sampleContent, len(sampleContent), "https://example.com")
+		require.NoError(t, err)
+		require.False(t, didTruncate)
+		require.Equal(t, sampleContent, truncated)
+	})
+
+	t.Run("KeepsWholeElements", func(t *testing.T) {
+		truncated, didTruncate, err := truncateHTML("<p>one</p><p>two</p><p>three</p>", 20, "")
+		require.NoError(t, err)
+		require.True(t, didTruncate)
+		require.Equal(t, "<p>one</p><p>two</p>", truncated)
+	})
+
+	t.Run("DescendsIntoElement", func(t *testing.T) {
+		truncated, _, err := truncateHTML(`<p>one two <a href="/three">three four</a> five</p>`, 44, "")
+		require.NoError(t, err)
+		require.Equal(t, `<p>one two <a href="/three">three…</a></p>`, truncated)
+	})
+
+	t.Run("NeverLeavesPartialEntities", func(t *testing.T) {
+		truncated, _, err := truncateHTML("<p>fish &amp; chips &amp; peas</p>", 26, "")
+		require.NoError(t, err)
+		require.Equal(t, "<p>fish &amp; chips…</p>", truncated)
+	})
+
+	t.Run("ContinueReading", func(t *testing.T) {
+		const continueURL = "https://brandur.org/sequences/030"
+
+		truncated, didTruncate, err := truncateHTML(sampleContent, 1000, continueURL)
+		require.NoError(t, err)
+		require.True(t, didTruncate)
+		require.LessOrEqual(t, len(truncated), 1000)
+		require.True(t, strings.HasSuffix(truncated,
+			`<p><a href="`+continueURL+`">`+continueReadingText+`</a></p>`))
+	})
+
+	t.Run("CanonicalizesAfterTruncation", func(t *testing.T) {
+		truncated, _, err := truncateHTML(sampleContent, 1500, "/sequences/030")
+		require.NoError(t, err)
+
+		canonicalized := canonicalizeURLs(truncated, sampleBaseURL)
+		require.Contains(t, canonicalized, `<a href="https://brandur.org/nanoglyphs/033-heroku#new-york">`)
+		require.Contains(t, canonicalized, `<a href="https://brandur.org/sequences/030">`)
+	})
+
+	t.Run("NothingFits", func(t *testing.T) {
+		truncated, didTruncate, err := truncateHTML("<p>a very long paragraph</p>", 5, "https://example.com")
+		require.NoError(t, err)
+		require.True(t, didTruncate)
+		require.Equal(t, "", truncated)
+	})
+}
+
+func TestTruncateText(t *testing.T) {
+	require.Equal(t, "one two…", truncateText("one two three", 10))
+	require.Equal(t, "", truncateText("one two three", 3))
+}